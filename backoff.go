@@ -0,0 +1,515 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// maxDuration is the largest representable [time.Duration].
+const maxDuration = time.Duration(math.MaxInt64)
+
+// WithExponentialBackoff returns a copy of the [Config] with a delay function
+// implementing exponential backoff.
+//
+// The delay before the retry following attempt i (starting at 1) is
+// base * factor^(i-1), so the first delay is base, the second is
+// base*factor, and so on. If the computation overflows, the delay is clamped
+// to the largest representable [time.Duration].
+//
+// Delays grow without bound; set [Config.MaxDelay] to cap them. The cap is
+// applied on top of the exponential curve, so once base*factor^(i-1)
+// reaches MaxDelay, all subsequent delays are equal to MaxDelay.
+func (c *Config) WithExponentialBackoff(base time.Duration, factor float64) Config {
+	return c.WithDelayFunc(func(i int) time.Duration {
+		return expDelay(base, factor, i)
+	})
+}
+
+// WithExponentialBackoffCapped is like [Config.WithExponentialBackoff], but
+// caps each delay at limit: the delay after attempt i is
+// min(limit, base*factor^(i-1)). Unlike [Config.MaxDelay], the cap is part
+// of the delay function, and is applied before jitter.
+func (c *Config) WithExponentialBackoffCapped(base time.Duration, factor float64, limit time.Duration) Config {
+	return c.WithDelayFunc(func(i int) time.Duration {
+		return min(limit, expDelay(base, factor, i))
+	})
+}
+
+// expDelay returns base * factor^(i-1), clamped to [0, maxDuration].
+func expDelay(base time.Duration, factor float64, i int) time.Duration {
+	d := float64(base) * math.Pow(factor, float64(i-1))
+	switch {
+	case math.IsNaN(d) || d <= 0:
+		return 0
+	case d >= float64(maxDuration):
+		return maxDuration
+	}
+	return time.Duration(d)
+}
+
+// WithExponential returns a copy of the [Config] with exponential backoff
+// using Delay as the initial interval, or 500ms if Delay is not set; the
+// multiplier is 2 unless changed with [Config.WithMultiplier].
+//
+// Unlike most other methods, WithExponential and the related WithMultiplier,
+// WithMaxInterval, and WithRandomization methods take Config by value, so
+// that calls can be chained:
+//
+//	cfg = cfg.WithExponential().WithMultiplier(1.5).WithMaxInterval(30 * time.Second).WithRandomization(0.3)
+//
+// The order of calls in a chain doesn't matter, as the settings are only
+// combined when Func is called.
+func (c Config) WithExponential() Config {
+	return c.withDelayFactory(func(c *Config) func(int) time.Duration {
+		initial, factor := c.Delay, c.multiplier
+		if initial <= 0 {
+			initial = 500 * time.Millisecond
+		}
+		if factor == 0 {
+			factor = 2
+		}
+		return func(i int) time.Duration { return expDelay(initial, factor, i) }
+	})
+}
+
+// WithMultiplier returns a copy of the [Config] with the multiplier used by
+// [Config.WithExponential] set to factor.
+func (c Config) WithMultiplier(factor float64) Config {
+	c.multiplier = factor
+	return c
+}
+
+// WithMaxInterval returns a copy of the [Config] with MaxDelay set to d.
+// It is meant to be chained with [Config.WithExponential].
+func (c Config) WithMaxInterval(d time.Duration) Config {
+	c.MaxDelay = d
+	return c
+}
+
+// WithRandomization is the same as [Config.WithJitter], but can be chained
+// with [Config.WithExponential].
+func (c Config) WithRandomization(fraction float64) Config {
+	return c.WithJitter(fraction)
+}
+
+// WithLinearBackoff returns a copy of the [Config] with a delay function
+// implementing linear backoff: the delay after attempt i (starting at 1) is
+// step*i.
+//
+// As there is no delay before the first attempt, the first delay applied is
+// step, before the second attempt; the next one is 2*step, before the third
+// attempt, and so on.
+func (c *Config) WithLinearBackoff(step time.Duration) Config {
+	return c.WithDelayFunc(func(i int) time.Duration {
+		if step > 0 && time.Duration(i) > maxDuration/step {
+			return maxDuration
+		}
+		return step * time.Duration(i)
+	})
+}
+
+// WithDelaySequence returns a copy of the [Config] with a delay function
+// that returns the given delays in order: as there is no delay before the
+// first attempt, attempt 2 waits for delays[0], attempt 3 for delays[1],
+// and so on. Once the sequence is over, the last delay is used for all the
+// remaining attempts. With no delays, there is no delay between attempts.
+func (c *Config) WithDelaySequence(delays ...time.Duration) Config {
+	delays = append([]time.Duration(nil), delays...)
+	return c.WithDelayFunc(func(i int) time.Duration {
+		if len(delays) == 0 || i < 1 {
+			return 0
+		}
+		return delays[min(i, len(delays))-1]
+	})
+}
+
+// WithFibonacciBackoff returns a copy of the [Config] with a delay function
+// implementing Fibonacci backoff: the delay after attempt i (starting at 1)
+// is base*fib(i), where fib(1) = fib(2) = 1, and fib(i) = fib(i-1)+fib(i-2).
+// This grows slower than exponential backoff with factor 2.
+// If the computation overflows, the delay is clamped to the largest
+// representable [time.Duration].
+func (c *Config) WithFibonacciBackoff(base time.Duration) Config {
+	return c.WithDelayFunc(func(i int) time.Duration {
+		if base <= 0 {
+			return 0
+		}
+		var a, b time.Duration = 0, 1
+		limit := maxDuration / base
+		for range i - 1 {
+			// b <= limit holds, so the check can't overflow, unlike a+b
+			if a > limit-b {
+				return maxDuration
+			}
+			a, b = b, a+b
+		}
+		return base * b
+	})
+}
+
+// BackoffByName returns a delay function for [Config.WithDelayFunc] that
+// implements the backoff strategy with the given name, configured by params.
+// It allows selecting a strategy in a configuration file. Durations are in
+// the format accepted by [time.ParseDuration]. Supported names and their
+// params are:
+//
+//   - "fixed": "delay" (required), as with [Config.Delay];
+//   - "exponential": "base" (required), "factor" (defaults to 2), and "max"
+//     (optional), as with [Config.WithExponentialBackoffCapped];
+//   - "linear": "step" (required), as with [Config.WithLinearBackoff];
+//   - "fibonacci": "base" (required), as with [Config.WithFibonacciBackoff].
+//
+// It returns an error for an unknown name, a missing, unknown, or
+// malformed parameter.
+func BackoffByName(name string, params map[string]string) (func(int) time.Duration, error) {
+	p := backoffParams{name: name, params: params}
+	var cfg Config
+	switch name {
+	case "fixed":
+		d := p.duration("delay")
+		cfg = cfg.WithDelayFunc(func(int) time.Duration { return d })
+	case "exponential":
+		base := p.duration("base")
+		factor := 2.0
+		if v, ok := p.get("factor", false); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 1 {
+				p.fail("factor", "must be a number not less than 1")
+			}
+			factor = f
+		}
+		limit := maxDuration
+		if _, ok := params["max"]; ok {
+			limit = p.duration("max")
+		}
+		cfg = cfg.WithExponentialBackoffCapped(base, factor, limit)
+	case "linear":
+		cfg = cfg.WithLinearBackoff(p.duration("step"))
+	case "fibonacci":
+		cfg = cfg.WithFibonacciBackoff(p.duration("base"))
+	default:
+		return nil, fmt.Errorf("retry: unknown backoff %q", name)
+	}
+	for k := range params {
+		if !p.used[k] {
+			p.fail(k, "unknown parameter")
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return cfg.delayFn, nil
+}
+
+// backoffParams parses params of [BackoffByName], recording the first error.
+type backoffParams struct {
+	name   string
+	params map[string]string
+	used   map[string]bool
+	err    error
+}
+
+func (p *backoffParams) fail(key, msg string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("retry: backoff %q: parameter %q: %s", p.name, key, msg)
+	}
+}
+
+func (p *backoffParams) get(key string, required bool) (string, bool) {
+	if p.used == nil {
+		p.used = make(map[string]bool)
+	}
+	p.used[key] = true
+	v, ok := p.params[key]
+	if !ok && required {
+		p.fail(key, "missing")
+	}
+	return v, ok
+}
+
+// duration parses the required duration parameter key.
+func (p *backoffParams) duration(key string) time.Duration {
+	v, ok := p.get(key, true)
+	if !ok {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		p.fail(key, "must be a non-negative duration")
+	}
+	return d
+}
+
+// WithDecorrelatedJitter returns a copy of the [Config] with a delay function
+// implementing the "decorrelated jitter" backoff strategy: each delay is
+// a random duration between base and three times the previous delay,
+// capped at limit. The first delay is based on base.
+//
+// Since each delay depends on the previous one, the state is kept per [Func]
+// call, so the returned Config can be safely used by concurrent calls.
+func (c *Config) WithDecorrelatedJitter(base, limit time.Duration) Config {
+	return c.withDelayFactory(func(c *Config) func(int) time.Duration {
+		prev := base
+		return func(int) time.Duration {
+			hi := prev
+			if hi < maxDuration/3 {
+				hi *= 3
+			} else {
+				hi = maxDuration
+			}
+			prev = min(limit, c.randBetween(base, hi))
+			return prev
+		}
+	})
+}
+
+// WithRandomExponential returns a copy of the [Config] with a delay function
+// implementing randomized exponential backoff: the first delay is base, and
+// each next one is the previous delay multiplied by a factor drawn uniformly
+// from [minFactor, maxFactor]. If the computation overflows, the delay is
+// clamped to the largest representable [time.Duration]; set
+// [Config.MaxDelay] to cap delays. Factors below 1 make delays shrink
+// instead; once a delay rounds down to zero, all the following ones are
+// zero too.
+//
+// Since each delay depends on the previous one, the state is kept per [Func]
+// call, so the returned Config can be safely used by concurrent calls.
+// Use [Config.WithRand] to control the source of randomness.
+func (c *Config) WithRandomExponential(base time.Duration, minFactor, maxFactor float64) Config {
+	return c.withDelayFactory(func(c *Config) func(int) time.Duration {
+		var prev time.Duration
+		var started bool
+		return func(int) time.Duration {
+			if !started {
+				prev, started = base, true
+				return prev
+			}
+			factor := minFactor + (maxFactor-minFactor)*c.randFloat()
+			if d := float64(prev) * factor; d < float64(maxDuration) {
+				prev = time.Duration(d)
+			} else {
+				prev = maxDuration
+			}
+			return prev
+		}
+	})
+}
+
+// WithFullJitter returns a copy of the [Config] with a delay function
+// implementing the "full jitter" backoff strategy: the delay after attempt i
+// (starting at 1) is a random duration between zero and
+// min(limit, base*2^(i-1)).
+//
+// Use [Config.WithRand] to control the source of randomness.
+func (c *Config) WithFullJitter(base, limit time.Duration) Config {
+	return c.withDelayFactory(func(c *Config) func(int) time.Duration {
+		return func(i int) time.Duration {
+			return c.randBetween(0, min(limit, expDelay(base, 2, i)))
+		}
+	})
+}
+
+// WithEqualJitter returns a copy of the [Config] with a delay function
+// implementing the "equal jitter" backoff strategy: the delay after attempt i
+// (starting at 1) is temp/2 plus a random duration between zero and temp/2,
+// where temp is min(limit, base*2^(i-1)). Unlike [Config.WithFullJitter],
+// it always waits for at least half of the exponential delay.
+//
+// Use [Config.WithRand] to control the source of randomness.
+func (c *Config) WithEqualJitter(base, limit time.Duration) Config {
+	return c.withDelayFactory(func(c *Config) func(int) time.Duration {
+		return func(i int) time.Duration {
+			temp := min(limit, expDelay(base, 2, i))
+			return c.randBetween(temp/2, temp)
+		}
+	})
+}
+
+// WithDeadlineAwareJitter returns a copy of the [Config] that spreads
+// retries over the time left until the context deadline, instead of using
+// the computed delays: the remaining time is split into equal slots, one for
+// each of the remaining attempts, and the delay before the next attempt is a
+// random duration between half a slot and a full slot. This way, attempts
+// are spread across the deadline window without bunching up at its start.
+//
+// If the context has no deadline, or the Config is [Config.Unlimited],
+// the computed delays are used as usual. MaxDelay and MinDelay don't apply
+// to delays derived from the deadline.
+//
+// Use [Config.WithRand] to control the source of randomness.
+func (c *Config) WithDeadlineAwareJitter() Config {
+	cfg := *c
+	cfg.deadlineJitter = true
+	return cfg
+}
+
+// deadlineDelay returns the delay set by WithDeadlineAwareJitter before the
+// next of the remaining attempts, or d if it can't be derived from ctx.
+func (c *Config) deadlineDelay(ctx context.Context, now time.Time, remaining int, d time.Duration) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok || c.Unlimited || remaining < 1 {
+		return d
+	}
+	left := deadline.Sub(now)
+	if left <= 0 {
+		return d
+	}
+	slot := left / time.Duration(remaining)
+	return c.randBetween(slot/2, slot)
+}
+
+// withDelayFactory returns a copy of the [Config] where the delay function
+// is constructed by fn on each Func call. Delay functions that keep state
+// between attempts or use the source of randomness of the Config must be set
+// this way.
+func (c *Config) withDelayFactory(fn func(*Config) func(int) time.Duration) Config {
+	cfg := *c
+	cfg.delayFn = nil
+	cfg.newDelayFn = fn
+	cfg.backoff = nil
+	return cfg
+}
+
+// WithJitter returns a copy of the [Config] that randomizes each delay
+// between attempts by ±fraction of its value. For example, with fraction 0.2
+// a computed delay d becomes a random delay in the [d*0.8, d*1.2] range.
+//
+// Jitter is applied on top of the delay computed from Delay or the delay
+// function, after it is capped at MaxDelay. The result is then capped at
+// MaxDelay again, so jitter never pushes delays above it. Negative results
+// are clamped to zero. Non-positive fraction disables jitter.
+func (c *Config) WithJitter(fraction float64) Config {
+	cfg := *c
+	cfg.jitter = fraction
+	cfg.jitterSeed = nil
+	return cfg
+}
+
+// Jitter returns the jitter fraction set with [Config.WithJitter],
+// or zero if jitter is not used.
+func (c *Config) Jitter() float64 { return c.jitter }
+
+// WithDeterministicJitter is like [Config.WithJitter], but derives the
+// jitter of each delay from seed and the attempt number instead of a source
+// of randomness, so the same Config produces the same delays on every run,
+// while the delays are still spread. This helps to reproduce timing issues.
+func (c *Config) WithDeterministicJitter(seed int64, fraction float64) Config {
+	cfg := *c
+	cfg.jitter = fraction
+	cfg.jitterSeed = &seed
+	return cfg
+}
+
+// seededFloat returns a number in [0.0,1.0) derived from seed and i with
+// the SplitMix64 mixing function.
+func seededFloat(seed int64, i int) float64 {
+	z := uint64(seed) + uint64(i)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11) / (1 << 53)
+}
+
+// WithRand returns a copy of the [Config] that uses r as a source of
+// randomness for jitter and randomized backoff strategies. By default, the
+// top-level functions of the math/rand package are used.
+//
+// A seeded [rand.Rand] is mostly useful in tests to get deterministic
+// delays; [CryptoRand] makes delays unpredictable. Note that [rand.Rand]
+// is not safe for concurrent use, so a Config using it must not be used by
+// concurrent calls.
+func (c *Config) WithRand(r Rand) Config {
+	cfg := *c
+	cfg.rand = r
+	return cfg
+}
+
+// applyJitter randomizes d, the delay after attempt i, according to the
+// configured jitter fraction.
+func (c *Config) applyJitter(i int, d time.Duration) time.Duration {
+	if c.jitter <= 0 || d <= 0 {
+		return d
+	}
+	r := c.randFloat
+	if c.jitterSeed != nil {
+		r = func() float64 { return seededFloat(*c.jitterSeed, i) }
+	}
+	span := float64(d) * c.jitter
+	j := float64(d) - span + 2*span*r()
+	switch {
+	case j <= 0:
+		return 0
+	case j >= float64(maxDuration):
+		return maxDuration
+	}
+	return time.Duration(j)
+}
+
+// randBetween returns a pseudo-random duration in [lo, hi].
+func (c *Config) randBetween(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(float64(hi-lo)*c.randFloat())
+}
+
+// randFloat returns a pseudo-random number in [0.0,1.0).
+func (c *Config) randFloat() float64 {
+	switch r := c.rand.(type) {
+	case nil:
+		return rand.Float64()
+	case interface{ Float64() float64 }:
+		return r.Float64()
+	default:
+		return float64(r.Int63n(1<<53)) / (1 << 53)
+	}
+}
+
+// StatefulBackoff is an exponential backoff that keeps its state between
+// calls, so that it can span several [Func] calls, such as reconnects of
+// a long-lived stream. Use [Config.WithStatefulBackoff] to use it with
+// a Config.
+//
+// StatefulBackoff is not safe for concurrent use; use a separate one for
+// each stream.
+type StatefulBackoff struct {
+	base   time.Duration
+	factor float64
+	limit  time.Duration
+	n      int // number of delays returned since the last reset
+}
+
+// NewStatefulBackoff returns a StatefulBackoff whose delays start at base
+// and grow by factor up to limit, as with
+// [Config.WithExponentialBackoffCapped].
+func NewStatefulBackoff(base time.Duration, factor float64, limit time.Duration) *StatefulBackoff {
+	return &StatefulBackoff{base: base, factor: factor, limit: limit}
+}
+
+// Next returns the next delay and advances the backoff.
+func (b *StatefulBackoff) Next() time.Duration {
+	b.n++
+	return min(b.limit, expDelay(b.base, b.factor, b.n))
+}
+
+// Reset makes the next delay equal to the base delay again.
+func (b *StatefulBackoff) Reset() { b.n = 0 }
+
+// WithStatefulBackoff returns a copy of the [Config] that takes delays
+// between attempts from b. If an attempt takes at least healthyAfter,
+// which means, for example, that a connection stayed healthy for a while,
+// b is reset once the attempt ends, so the delay before the next attempt
+// is short again.
+//
+// Since b keeps state across calls, [Config.Schedule] and
+// [Config.DelayForAttempt] advance it too.
+func (c *Config) WithStatefulBackoff(b *StatefulBackoff, healthyAfter time.Duration) Config {
+	cfg := c.WithDelayFunc(func(int) time.Duration { return b.Next() })
+	cfg.backoff = b
+	cfg.healthyAfter = healthyAfter
+	return cfg
+}
@@ -0,0 +1,456 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestWithExponentialBackoff(t *testing.T) {
+	var cfg retry.Config
+	delayFn := retry.DelayFunc(cfg.WithExponentialBackoff(10*time.Millisecond, 2))
+	want := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		80 * time.Millisecond,
+	}
+	for i, w := range want {
+		if d := delayFn(i + 1); d != w {
+			t.Fatalf("attempt %d: got delay %v, want %v", i+1, d, w)
+		}
+	}
+	if d := delayFn(1000); d != time.Duration(math.MaxInt64) {
+		t.Fatalf("got delay %v on overflow, want %v", d, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	base := retry.Config{Delay: 100 * time.Millisecond}
+	cfg := base.WithJitter(0.2)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	cfg2 := base.WithJitter(0.2)
+	cfg2 = cfg2.WithRand(rand.New(rand.NewSource(1)))
+	lo, hi := 80*time.Millisecond, 120*time.Millisecond
+	var distinct bool
+	for i := 1; i <= 100; i++ {
+		d := retry.Delay(cfg, i)
+		if d < lo || d > hi {
+			t.Fatalf("attempt %d: delay %v is outside of [%v, %v]", i, d, lo, hi)
+		}
+		if d2 := retry.Delay(cfg2, i); d != d2 {
+			t.Fatalf("attempt %d: got different delays %v and %v with the same seed", i, d, d2)
+		}
+		if d != base.Delay {
+			distinct = true
+		}
+	}
+	if !distinct {
+		t.Fatal("jitter did not change any delay")
+	}
+	var zero retry.Config
+	zero = zero.WithJitter(2)
+	zero.Delay = time.Second
+	for i := 1; i <= 100; i++ {
+		if d := retry.Delay(zero, i); d < 0 {
+			t.Fatalf("got negative delay %v", d)
+		}
+	}
+}
+
+func TestWithDecorrelatedJitter(t *testing.T) {
+	const base, limit = 10 * time.Millisecond, time.Second
+	var cfg retry.Config
+	cfg = cfg.WithDecorrelatedJitter(base, limit)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	delayFn := retry.DelayFunc(cfg)
+	var sawLimit bool
+	for i := 1; i <= 100; i++ {
+		d := delayFn(i)
+		if d < base || d > limit {
+			t.Fatalf("attempt %d: delay %v is outside of [%v, %v]", i, d, base, limit)
+		}
+		sawLimit = sawLimit || d == limit
+	}
+	if !sawLimit {
+		t.Fatalf("delays never reached %v", limit)
+	}
+}
+
+func TestWithRandomExponential(t *testing.T) {
+	const base = 10 * time.Millisecond
+	const minFactor, maxFactor = 1.5, 2.5
+	var cfg retry.Config
+	cfg = cfg.WithRandomExponential(base, minFactor, maxFactor)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	delayFn := retry.DelayFunc(cfg)
+	if d := delayFn(1); d != base {
+		t.Fatalf("got first delay %v, want %v", d, base)
+	}
+	prev := base
+	for i := 2; i <= 20; i++ {
+		d := delayFn(i)
+		lo := time.Duration(float64(prev) * minFactor)
+		hi := time.Duration(float64(prev) * maxFactor)
+		if d < lo || d > hi {
+			t.Fatalf("attempt %d: delay %v is outside of [%v, %v]", i, d, lo, hi)
+		}
+		prev = d
+	}
+	for i := 21; i <= 200; i++ {
+		prev = delayFn(i)
+	}
+	if prev != math.MaxInt64 {
+		t.Fatalf("got delay %v after many attempts, want it clamped to %v", prev, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestWithRandomExponentialShrinking(t *testing.T) {
+	var cfg retry.Config
+	cfg = cfg.WithRandomExponential(time.Nanosecond, 0.1, 0.2)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	// once the delay rounds down to zero, it doesn't restart at base
+	want := []time.Duration{time.Nanosecond, 0, 0, 0, 0}
+	if got := cfg.Schedule(6); !slices.Equal(got, want) {
+		t.Fatalf("got schedule %v, want %v", got, want)
+	}
+}
+
+func TestWithLinearBackoff(t *testing.T) {
+	var delays []time.Duration
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithLinearBackoff(time.Second)
+	delayFn := retry.DelayFunc(cfg)
+	cfg = cfg.WithDelayFunc(func(i int) time.Duration {
+		d := delayFn(i)
+		delays = append(delays, d)
+		return d
+	})
+	cfg.MaxDelay = time.Nanosecond // don't actually wait
+	_ = retry.Func(context.Background(), cfg, func() error { return errors.New("boom") })
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	if !slices.Equal(delays, want) {
+		t.Fatalf("got delays %v, want %v", delays, want)
+	}
+	if d := delayFn(math.MaxInt); d != time.Duration(math.MaxInt64) {
+		t.Fatalf("got delay %v on overflow, want %v", d, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestWithFibonacciBackoff(t *testing.T) {
+	var cfg retry.Config
+	delayFn := retry.DelayFunc(cfg.WithFibonacciBackoff(time.Second))
+	want := []time.Duration{1, 1, 2, 3, 5, 8, 13, 21}
+	for i, w := range want {
+		if d := delayFn(i + 1); d != w*time.Second {
+			t.Fatalf("attempt %d: got delay %v, want %v", i+1, d, w*time.Second)
+		}
+	}
+	if d := delayFn(1000); d != time.Duration(math.MaxInt64) {
+		t.Fatalf("got delay %v on overflow, want %v", d, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestWithFibonacciBackoffOverflow(t *testing.T) {
+	var cfg retry.Config
+	delayFn := retry.DelayFunc(cfg.WithFibonacciBackoff(time.Nanosecond))
+	// fib(92) is the largest Fibonacci number that fits into int64
+	if d, want := delayFn(92), time.Duration(7540113804746346429); d != want {
+		t.Fatalf("attempt 92: got delay %v, want %v", d, want)
+	}
+	for i := 93; i <= 1000; i++ {
+		if d := delayFn(i); d != time.Duration(math.MaxInt64) {
+			t.Fatalf("attempt %d: got delay %v on overflow, want %v", i, d, time.Duration(math.MaxInt64))
+		}
+	}
+}
+
+func TestWithFullJitter(t *testing.T) {
+	const base, limit = 10 * time.Millisecond, time.Second
+	var cfg retry.Config
+	cfg = cfg.WithFullJitter(base, limit)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	delayFn := retry.DelayFunc(cfg)
+	for i := 1; i <= 1000; i++ {
+		d := delayFn(i)
+		if hi := min(limit, base<<min(i-1, 20)); d < 0 || d > hi {
+			t.Fatalf("attempt %d: delay %v is outside of [0, %v]", i, d, hi)
+		}
+	}
+}
+
+func TestWithEqualJitter(t *testing.T) {
+	const base, limit = 10 * time.Millisecond, time.Second
+	var cfg retry.Config
+	cfg = cfg.WithEqualJitter(base, limit)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	delayFn := retry.DelayFunc(cfg)
+	for i := 1; i <= 1000; i++ {
+		d := delayFn(i)
+		if temp := min(limit, base<<min(i-1, 20)); d < temp/2 || d > temp {
+			t.Fatalf("attempt %d: delay %v is outside of [%v, %v]", i, d, temp/2, temp)
+		}
+	}
+}
+
+func TestWithExponentialBackoffCapped(t *testing.T) {
+	var cfg retry.Config
+	cfg = cfg.WithExponentialBackoffCapped(time.Second, 2, 5*time.Second)
+	want := []time.Duration{0, 0, 1, 2, 4, 5, 5}
+	for i, w := range want {
+		if d := cfg.DelayForAttempt(i); d != w*time.Second {
+			t.Fatalf("attempt %d: got delay %v, want %v", i, d, w*time.Second)
+		}
+	}
+	if d := cfg.DelayForAttempt(10000); d != 5*time.Second {
+		t.Fatalf("got delay %v on overflow, want %v", d, 5*time.Second)
+	}
+}
+
+func ExampleConfig_WithExponential() {
+	cfg := retry.Config{Delay: time.Second}
+	cfg = cfg.WithExponential().WithMultiplier(1.5).WithMaxInterval(5 * time.Second)
+	for i := 2; i <= 7; i++ {
+		fmt.Printf("delay before attempt %d: %v\n", i, cfg.DelayForAttempt(i))
+	}
+	// Output:
+	// delay before attempt 2: 1s
+	// delay before attempt 3: 1.5s
+	// delay before attempt 4: 2.25s
+	// delay before attempt 5: 3.375s
+	// delay before attempt 6: 5s
+	// delay before attempt 7: 5s
+}
+
+func TestConfigWithExponential(t *testing.T) {
+	var cfg retry.Config
+	cfg = cfg.WithExponential()
+	for i, want := range []time.Duration{500, 1000, 2000, 4000} {
+		if d := cfg.DelayForAttempt(i + 2); d != want*time.Millisecond {
+			t.Fatalf("attempt %d: got delay %v, want %v", i+2, d, want*time.Millisecond)
+		}
+	}
+	cfg = cfg.WithRandomization(0.5).WithMaxInterval(3 * time.Second).WithMultiplier(3)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	for i, want := range []time.Duration{500, 1500, 3000, 3000} {
+		lo, hi := want*time.Millisecond/2, want*time.Millisecond*3/2
+		if d := cfg.DelayForAttempt(i + 2); d < lo || d > hi {
+			t.Fatalf("attempt %d: delay %v is outside of [%v, %v]", i+2, d, lo, hi)
+		}
+	}
+}
+
+func TestWithJitterMaxDelay(t *testing.T) {
+	cfg := retry.Config{Delay: time.Minute, MaxDelay: time.Second}
+	cfg = cfg.WithJitter(0.5)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	var below bool
+	for i := 2; i <= 100; i++ {
+		d := cfg.DelayForAttempt(i)
+		if d > cfg.MaxDelay {
+			t.Fatalf("attempt %d: delay %v exceeds MaxDelay %v", i, d, cfg.MaxDelay)
+		}
+		if d < cfg.MaxDelay/2 {
+			t.Fatalf("attempt %d: delay %v is less than jitter allows", i, d)
+		}
+		below = below || d < cfg.MaxDelay
+	}
+	if !below {
+		t.Fatal("jitter did not change any delay")
+	}
+}
+
+func TestStatefulBackoff(t *testing.T) {
+	b := retry.NewStatefulBackoff(time.Second, 2, 5*time.Second)
+	var got []time.Duration
+	for range 4 {
+		got = append(got, b.Next())
+	}
+	b.Reset()
+	got = append(got, b.Next())
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, time.Second}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got delays %v, want %v", got, want)
+	}
+}
+
+func TestConfigWithStatefulBackoff(t *testing.T) {
+	clk := &fakeClock{auto: true}
+	b := retry.NewStatefulBackoff(time.Second, 2, time.Minute)
+	cfg := retry.Config{
+		Unlimited: true,
+		RetryOn:   func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithStatefulBackoff(b, time.Hour)
+	cfg = cfg.WithClock(clk)
+	// how long each connection stays alive before failing
+	lifetimes := []time.Duration{0, 0, 2 * time.Hour, 0, 0}
+	var starts []time.Duration
+	var n int
+	fn := func() error {
+		starts = append(starts, clk.Now().Sub(time.Time{}))
+		if n == len(lifetimes) {
+			return nil
+		}
+		clk.advance(lifetimes[n])
+		n++
+		return errors.New("disconnected")
+	}
+	if err := retry.Func(context.Background(), cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	h := 2 * time.Hour
+	want := []time.Duration{
+		0,
+		time.Second,
+		3 * time.Second,
+		// stayed alive for 2h, so the backoff is reset
+		3*time.Second + h + time.Second,
+		3*time.Second + h + 3*time.Second,
+		3*time.Second + h + 7*time.Second,
+	}
+	if !slices.Equal(starts, want) {
+		t.Fatalf("got attempts started at %v, want %v", starts, want)
+	}
+}
+
+func TestBackoffByName(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		params map[string]string
+		want   []time.Duration
+	}{
+		{"fixed", map[string]string{"delay": "1s"}, []time.Duration{time.Second, time.Second, time.Second}},
+		{"exponential", map[string]string{"base": "1s"}, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}},
+		{"exponential", map[string]string{"base": "1s", "factor": "3", "max": "5s"}, []time.Duration{time.Second, 3 * time.Second, 5 * time.Second}},
+		{"linear", map[string]string{"step": "2s"}, []time.Duration{2 * time.Second, 4 * time.Second, 6 * time.Second}},
+		{"fibonacci", map[string]string{"base": "1s"}, []time.Duration{time.Second, time.Second, 2 * time.Second}},
+	} {
+		fn, err := retry.BackoffByName(tc.name, tc.params)
+		if err != nil {
+			t.Errorf("%s %v: got unexpected error: %v", tc.name, tc.params, err)
+			continue
+		}
+		var got []time.Duration
+		for i := range tc.want {
+			got = append(got, fn(i+1))
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s %v: got delays %v, want %v", tc.name, tc.params, got, tc.want)
+		}
+	}
+	for _, tc := range []struct {
+		name   string
+		params map[string]string
+	}{
+		{"quadratic", nil},
+		{"fixed", nil},
+		{"fixed", map[string]string{"delay": "soon"}},
+		{"fixed", map[string]string{"delay": "-1s"}},
+		{"fixed", map[string]string{"delay": "1s", "jitter": "0.1"}},
+		{"exponential", map[string]string{"base": "1s", "factor": "x"}},
+		{"exponential", map[string]string{"base": "1s", "factor": "0.5"}},
+		{"linear", map[string]string{"base": "1s"}},
+	} {
+		if _, err := retry.BackoffByName(tc.name, tc.params); err == nil {
+			t.Errorf("%s %v: expected an error, got nil", tc.name, tc.params)
+		}
+	}
+}
+
+func TestWithDelaySequence(t *testing.T) {
+	var cfg retry.Config
+	seq := []time.Duration{time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second}
+	cfg = cfg.WithDelaySequence(seq...)
+	seq[0] = time.Hour // must not affect the Config
+	want := []time.Duration{time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second}
+	if got := cfg.Schedule(len(want) + 1); !slices.Equal(got, want) {
+		t.Fatalf("got delays %v, want %v", got, want)
+	}
+	cfg = cfg.WithDelaySequence()
+	if got := cfg.Schedule(3); !slices.Equal(got, []time.Duration{0, 0}) {
+		t.Fatalf("got delays %v for an empty sequence, want [0 0]", got)
+	}
+}
+
+func TestWithDeadlineAwareJitter(t *testing.T) {
+	now := time.Now()
+	const window = 10 * time.Second
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(window))
+	defer cancel()
+	clk := &fakeClock{now: now, auto: true}
+	cfg := retry.Config{
+		MaxAttempts: 6,
+		Delay:       time.Hour, // replaced by delays derived from the deadline
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithDeadlineAwareJitter()
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	cfg = cfg.WithClock(clk)
+	var starts []time.Duration
+	n, err := retry.FuncAttempts(ctx, cfg, func() error {
+		starts = append(starts, clk.Now().Sub(now))
+		return errors.New("boom")
+	})
+	if err == nil || n != cfg.MaxAttempts {
+		t.Fatalf("got error %v after %d attempts, want error after %d", err, n, cfg.MaxAttempts)
+	}
+	for i := 1; i < len(starts); i++ {
+		left := window - starts[i-1]
+		slot := left / time.Duration(cfg.MaxAttempts-i)
+		if d := starts[i] - starts[i-1]; d < slot/2 || d > slot {
+			t.Errorf("delay before attempt %d: got %v, want it in [%v, %v]", i+1, d, slot/2, slot)
+		}
+	}
+	if last := starts[len(starts)-1]; last < window/2 || last >= window {
+		t.Errorf("last attempt started at %v, want it in the second half of %v", last, window)
+	}
+}
+
+func TestWithDeterministicJitter(t *testing.T) {
+	newConfig := func(seed int64) retry.Config {
+		cfg := retry.Config{Delay: time.Second}
+		return cfg.WithDeterministicJitter(seed, 0.5)
+	}
+	delays := func(cfg retry.Config) []time.Duration {
+		var out []time.Duration
+		for i := 1; i <= 10; i++ {
+			out = append(out, retry.Delay(cfg, i))
+		}
+		return out
+	}
+	d1, d2 := delays(newConfig(42)), delays(newConfig(42))
+	if !slices.Equal(d1, d2) {
+		t.Fatalf("same seed produced different delays: %v and %v", d1, d2)
+	}
+	if d3 := delays(newConfig(43)); slices.Equal(d1, d3) {
+		t.Fatalf("different seeds produced the same delays: %v", d1)
+	}
+	distinct := make(map[time.Duration]bool)
+	for i, d := range d1 {
+		if d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("delay %d: got %v, want it in [500ms, 1.5s]", i+1, d)
+		}
+		distinct[d] = true
+	}
+	if len(distinct) < 5 {
+		t.Fatalf("delays are not spread: %v", d1)
+	}
+	// switching to regular jitter drops the seed
+	cfg := newConfig(42)
+	cfg = cfg.WithJitter(0.5)
+	cfg = cfg.WithRand(rand.New(rand.NewSource(1)))
+	if slices.Equal(delays(cfg), d1) {
+		t.Fatal("WithJitter kept the deterministic jitter")
+	}
+}
@@ -0,0 +1,23 @@
+package retry
+
+import "context"
+
+// Binder retries functions with a context and [Config] bound to it by
+// [Bind], for call sites that make many retried calls with the same ones.
+type Binder struct {
+	ctx context.Context
+	cfg Config
+}
+
+// Bind returns a [Binder] that retries functions with ctx and cfg. Both are
+// captured when Bind is called: later changes to the Config the caller holds
+// don't affect the Binder, and once ctx is canceled, all subsequent calls of
+// [Binder.Do] return right away without calling their functions.
+func Bind(ctx context.Context, cfg Config) Binder {
+	return Binder{ctx: ctx, cfg: cfg}
+}
+
+// Do retries fn as [Func] does, with the bound context and Config.
+func (b Binder) Do(fn func() error) error {
+	return Func(b.ctx, b.cfg, fn)
+}
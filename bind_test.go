@@ -0,0 +1,51 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/artyom/retry"
+)
+
+func TestBind(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := retry.Bind(ctx, cfg)
+	cfg.MaxAttempts = 1 // doesn't affect b
+
+	var calls int
+	errBoom := errors.New("boom")
+	if err := b.Do(func() error { calls++; return errBoom }); err != errBoom {
+		t.Fatalf("got error %v, want %v", err, errBoom)
+	}
+	if calls != 3 {
+		t.Fatalf("got %d calls, want 3", calls)
+	}
+
+	calls = 0
+	if err := b.Do(func() error {
+		if calls++; calls < 2 {
+			return errBoom
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+
+	cancel()
+	calls = 0
+	if err := b.Do(func() error { calls++; return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	if calls != 0 {
+		t.Fatalf("got %d calls after cancel, want 0", calls)
+	}
+}
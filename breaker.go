@@ -0,0 +1,135 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by [Func] when the [CircuitBreaker] set in
+// [Config.Breaker] doesn't allow the next attempt.
+var ErrCircuitOpen = errors.New("retry: circuit breaker is open")
+
+// BreakerState is a state of a [CircuitBreaker].
+type BreakerState int
+
+const (
+	// BreakerClosed means attempts are allowed.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen means attempts are rejected with [ErrCircuitOpen].
+	BreakerOpen
+	// BreakerHalfOpen means a single trial attempt is allowed to check
+	// whether the failures are over.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// CircuitBreaker stops attempts made by all [Func] calls sharing it once the
+// attempts fail too often, giving the failing dependency time to recover.
+// See [Config.Breaker].
+//
+// The breaker starts closed. After threshold consecutive failed attempts it
+// opens, and rejects all attempts for the cooldown period. Then it becomes
+// half-open and allows a single trial attempt: if it succeeds, the breaker
+// closes, otherwise it opens again for another cooldown period.
+//
+// CircuitBreaker is safe for concurrent use.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the breaker was last opened
+	probing  bool      // whether a trial attempt is in progress while half-open
+}
+
+// NewCircuitBreaker returns a new closed [CircuitBreaker] that opens after
+// threshold consecutive failures, and stays open for cooldown. A threshold
+// less than 1 is treated as 1.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: max(1, threshold), cooldown: cooldown}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.update()
+	return b.state
+}
+
+// allow is called before each attempt, and reports whether it is allowed.
+// Each allowed attempt must be followed by a call to done.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.update()
+	switch b.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// done is called after each allowed attempt with its outcome.
+func (b *CircuitBreaker) done(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case success:
+		b.state, b.failures = BreakerClosed, 0
+	case b.state == BreakerHalfOpen:
+		b.open()
+	case b.state == BreakerClosed:
+		if b.failures++; b.failures >= b.threshold {
+			b.open()
+		}
+	}
+	b.probing = false
+}
+
+// wrap wraps fn so that the outcome of each call is reported with done,
+// including a panic, which counts as a failure: otherwise a panicking trial
+// attempt would leave the breaker half-open, rejecting all attempts.
+func (b *CircuitBreaker) wrap(fn func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) (err error) {
+		success := false
+		defer func() { b.done(success) }()
+		err = fn(ctx)
+		success = err == nil
+		return err
+	}
+}
+
+// open opens the breaker. b.mu must be held.
+func (b *CircuitBreaker) open() {
+	b.state, b.failures, b.openedAt = BreakerOpen, 0, time.Now()
+}
+
+// update moves an open breaker to the half-open state once the cooldown
+// period is over. b.mu must be held.
+func (b *CircuitBreaker) update() {
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+}
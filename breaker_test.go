@@ -0,0 +1,105 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := retry.NewCircuitBreaker(2, cooldown)
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return err != nil },
+		Breaker:     b,
+	}
+	errBoom := errors.New("boom")
+	var calls int
+	fail := func() error { calls++; return errBoom }
+	succeed := func() error { calls++; return nil }
+
+	err := retry.Func(context.Background(), cfg, fail)
+	if err != retry.ErrCircuitOpen {
+		t.Fatalf("got unexpected error %v, want %v", err, retry.ErrCircuitOpen)
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+	if s := b.State(); s != retry.BreakerOpen {
+		t.Fatalf("got state %v, want %v", s, retry.BreakerOpen)
+	}
+
+	calls = 0
+	if err := retry.Func(context.Background(), cfg, succeed); err != retry.ErrCircuitOpen || calls != 0 {
+		t.Fatalf("got error %v after %d calls, want %v without calls", err, calls, retry.ErrCircuitOpen)
+	}
+
+	time.Sleep(cooldown)
+	if s := b.State(); s != retry.BreakerHalfOpen {
+		t.Fatalf("got state %v, want %v", s, retry.BreakerHalfOpen)
+	}
+	// a failed trial attempt opens the breaker again
+	if err := retry.Func(context.Background(), cfg, fail); err != retry.ErrCircuitOpen || calls != 1 {
+		t.Fatalf("got error %v after %d calls, want %v after 1 call", err, calls, retry.ErrCircuitOpen)
+	}
+	if s := b.State(); s != retry.BreakerOpen {
+		t.Fatalf("got state %v, want %v", s, retry.BreakerOpen)
+	}
+
+	time.Sleep(cooldown)
+	calls = 0
+	if err := retry.Func(context.Background(), cfg, succeed); err != nil || calls != 1 {
+		t.Fatalf("got error %v after %d calls, want success after 1 call", err, calls)
+	}
+	if s := b.State(); s != retry.BreakerClosed {
+		t.Fatalf("got state %v, want %v", s, retry.BreakerClosed)
+	}
+}
+
+func TestCircuitBreakerJoinErrors(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return err != nil },
+		Breaker:     retry.NewCircuitBreaker(1, time.Hour),
+		JoinErrors:  true,
+	}
+	errBoom := errors.New("boom")
+	err := retry.Func(context.Background(), cfg, func() error { return errBoom })
+	for _, target := range []error{errBoom, retry.ErrCircuitOpen} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v, %v) is false", err, target)
+		}
+	}
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	const cooldown = 20 * time.Millisecond
+	b := retry.NewCircuitBreaker(1, cooldown)
+	cfg := retry.Config{Breaker: b}
+	retry.Func(context.Background(), cfg, func() error { return errors.New("boom") })
+	time.Sleep(cooldown)
+	if s := b.State(); s != retry.BreakerHalfOpen {
+		t.Fatalf("got state %v, want %v", s, retry.BreakerHalfOpen)
+	}
+
+	// the trial attempt panics, and the panic is recovered by the caller
+	func() {
+		defer func() { recover() }()
+		retry.Func(context.Background(), cfg, func() error { panic("boom") })
+	}()
+	if s := b.State(); s != retry.BreakerOpen {
+		t.Fatalf("got state %v after panic, want %v", s, retry.BreakerOpen)
+	}
+
+	time.Sleep(cooldown)
+	if err := retry.Func(context.Background(), cfg, func() error { return nil }); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if s := b.State(); s != retry.BreakerClosed {
+		t.Fatalf("got state %v, want %v", s, retry.BreakerClosed)
+	}
+}
@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// RetryBudget limits the number of retries made by all [Func] calls sharing
+// it, so that a storm of failures doesn't result in an unbounded number of
+// retries system-wide. See [Config.Budget].
+//
+// The budget is a token bucket: each Func call deposits ratio tokens,
+// and each retry withdraws one token; if there is no token left, Func stops
+// retrying. Additionally, the bucket is refilled at minPerSec tokens per
+// second, so that some retries are always allowed even if the call rate is
+// low. The bucket starts full and holds up to max(10, 10*minPerSec) tokens.
+//
+// RetryBudget is safe for concurrent use.
+type RetryBudget struct {
+	ratio     float64
+	minPerSec float64
+	capacity  float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewRetryBudget returns a new [RetryBudget] allowing retries for the ratio
+// of calls (0.1 means a retry per every 10 calls), plus minPerSec retries
+// per second.
+func NewRetryBudget(ratio float64, minPerSec float64) *RetryBudget {
+	ratio, minPerSec = max(0, ratio), max(0, minPerSec)
+	capacity := max(10, 10*minPerSec)
+	return &RetryBudget{
+		ratio:     ratio,
+		minPerSec: minPerSec,
+		capacity:  capacity,
+		tokens:    capacity,
+		last:      time.Now(),
+	}
+}
+
+// deposit is called once per Func call.
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	b.tokens = min(b.capacity, b.tokens+b.ratio)
+}
+
+// withdraw is called before each retry, and reports whether it is allowed.
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refill adds tokens accumulated since the last call. b.mu must be held.
+func (b *RetryBudget) refill() {
+	now := time.Now()
+	if b.minPerSec > 0 {
+		b.tokens = min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.minPerSec)
+	}
+	b.last = now
+}
@@ -0,0 +1,40 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/artyom/retry"
+)
+
+func TestRetryBudget(t *testing.T) {
+	budget := retry.NewRetryBudget(0.5, 0)
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		RetryOn:     func(err error) bool { return err != nil },
+		Budget:      budget,
+	}
+	fail := func() error { return errors.New("boom") }
+
+	// the budget starts full with 10 tokens, each call deposits half a token,
+	// and each retry takes one
+	var attempts []int
+	for range 6 {
+		n, _ := retry.FuncAttempts(context.Background(), cfg, fail)
+		attempts = append(attempts, n)
+	}
+	// tokens after each deposit: 10, 7.5, 5, 2.5, 1, 0.5
+	want := []int{4, 4, 4, 3, 2, 1}
+	for i := range want {
+		if attempts[i] != want[i] {
+			t.Fatalf("got attempts per call %v, want %v", attempts, want)
+		}
+	}
+
+	var calls int
+	succeed := func() error { calls++; return nil }
+	if err := retry.Func(context.Background(), cfg, succeed); err != nil || calls != 1 {
+		t.Fatalf("got error %v after %d calls with exhausted budget, want success after 1 call", err, calls)
+	}
+}
@@ -0,0 +1,121 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is a source of time used by [Func] to measure elapsed time and
+// wait between attempts. It allows tests to substitute real time with
+// a fake one, see [Config.WithClock].
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a new Timer that sends the current time on its
+	// channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer created by a [Clock]. Its methods have the same
+// semantics as the methods of [time.Timer].
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// WithClock returns a copy of the [Config] that uses clk as a source of time.
+// By default, the real time from the time package is used.
+func (c *Config) WithClock(clk Clock) Config {
+	cfg := *c
+	cfg.clock = clk
+	return cfg
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                 { return time.Now() }
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+func (c *Config) getClock() Clock {
+	if c.clock != nil {
+		return c.clock
+	}
+	return realClock{}
+}
+
+// sleep waits for the duration d, or until the context is canceled,
+// in which case it returns the cause of the context cancellation.
+//
+// If the context deadline comes before d elapses, sleep returns
+// immediately without waiting.
+func (c *Config) sleep(ctx context.Context, d time.Duration) error {
+	return c.sleepTimer(ctx, d, nil)
+}
+
+// sleepTimer is like sleep, but if t is not nil, the timer it points to is
+// reused between calls instead of creating a new one for every delay. If *t
+// is nil, it is set to a new timer on first use; the caller is responsible
+// for stopping it once done.
+func (c *Config) sleepTimer(ctx context.Context, d time.Duration, t *Timer) error {
+	if d <= 0 {
+		return contextErr(ctx)
+	}
+	clk := c.getClock()
+	// with a Trigger set, the delay may end before the deadline
+	if deadline, ok := ctx.Deadline(); ok && c.Trigger == nil && deadline.Sub(clk.Now()) < d {
+		if err := contextErr(ctx); err != nil {
+			return err
+		}
+		return context.DeadlineExceeded
+	}
+	var timer Timer
+	switch {
+	case t == nil:
+		timer = clk.NewTimer(d)
+		defer timer.Stop()
+	case *t == nil:
+		timer = clk.NewTimer(d)
+		*t = timer
+	default:
+		timer = *t
+		resetTimer(timer, d)
+	}
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C():
+		return nil
+	case <-c.Trigger:
+		return nil
+	}
+}
+
+// resetTimer stops t, drains its channel if the timer has already fired but
+// its value was not received, and resets it to fire after d. Draining
+// matters for Timer implementations with the pre-Go 1.23 semantics of
+// [time.Timer], where a stale value could otherwise end the next delay
+// early.
+func resetTimer(t Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C():
+		default:
+		}
+	}
+	t.Reset(d)
+}
+
+// contextErr returns the cause of the context cancellation, or nil if the
+// context is not canceled. If no cause was set, this is the Context.Err
+// value.
+func contextErr(ctx context.Context) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	return context.Cause(ctx)
+}
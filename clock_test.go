@@ -0,0 +1,174 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+// fakeClock is a retry.Clock that doesn't depend on real time.
+// If auto is set, its timers fire immediately, advancing the clock
+// by the timer duration; otherwise they never fire.
+type fakeClock struct {
+	mu   sync.Mutex
+	now  time.Time
+	auto bool
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) retry.Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+func (t *fakeTimer) Stop() bool          { return false }
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.auto {
+		c.now = c.now.Add(d)
+		t.c <- c.now
+	}
+	return false
+}
+
+func TestWithClock(t *testing.T) {
+	t.Run("cancel", func(t *testing.T) {
+		cfg := retry.Config{
+			MaxAttempts: 10,
+			RetryOn:     func(err error) bool { return err != nil },
+			Delay:       time.Millisecond,
+		}
+		cfg = cfg.WithClock(&fakeClock{})
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int
+		fn := func() error {
+			calls++
+			time.AfterFunc(10*time.Millisecond, cancel)
+			return errors.New("boom")
+		}
+		err := retry.Func(ctx, cfg, fn)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got unexpected error %v, want %v", err, context.Canceled)
+		}
+		if calls != 1 {
+			t.Fatalf("expecting exactly 1 function call, got %d", calls)
+		}
+	})
+	t.Run("maxElapsedTime", func(t *testing.T) {
+		clk := &fakeClock{auto: true}
+		cfg := retry.Config{
+			MaxAttempts:    100,
+			RetryOn:        func(err error) bool { return err != nil },
+			Delay:          time.Minute,
+			MaxElapsedTime: 10 * time.Minute,
+		}
+		cfg = cfg.WithClock(clk)
+		n, _ := retry.FuncAttempts(context.Background(), cfg, func() error { return errors.New("boom") })
+		if n != 11 {
+			t.Fatalf("got %d attempts, want 11", n)
+		}
+		if d := clk.Now().Sub(time.Time{}); d != 10*time.Minute {
+			t.Fatalf("clock advanced by %v, want %v", d, 10*time.Minute)
+		}
+	})
+}
+
+// countingClock is a fakeClock that counts created timers.
+type countingClock struct {
+	fakeClock
+	timers int
+}
+
+func (c *countingClock) NewTimer(d time.Duration) retry.Timer {
+	c.timers++
+	return c.fakeClock.NewTimer(d)
+}
+
+func TestFuncReusesTimer(t *testing.T) {
+	delays := []time.Duration{time.Second, 3 * time.Second, 2 * time.Second, 5 * time.Second}
+	clk := &countingClock{fakeClock: fakeClock{auto: true}}
+	cfg := retry.Config{
+		MaxAttempts: len(delays) + 1,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithDelaySequence(delays...)
+	cfg = cfg.WithClock(clk)
+	var times []time.Time
+	err := retry.Func(context.Background(), cfg, func() error {
+		times = append(times, clk.Now())
+		return errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected to get error from retry.Func, but got nil")
+	}
+	if clk.timers != 1 {
+		t.Fatalf("got %d timers created, want 1", clk.timers)
+	}
+	if len(times) != len(delays)+1 {
+		t.Fatalf("got %d calls, want %d", len(times), len(delays)+1)
+	}
+	for i, d := range delays {
+		if got := times[i+1].Sub(times[i]); got != d {
+			t.Errorf("delay before attempt %d: got %v, want %v", i+2, got, d)
+		}
+	}
+}
+
+func TestFuncReusedTimerDelays(t *testing.T) {
+	delays := []time.Duration{20 * time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond}
+	cfg := retry.Config{
+		MaxAttempts: len(delays) + 1,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithDelaySequence(delays...)
+	var times []time.Time
+	retry.Func(context.Background(), cfg, func() error {
+		times = append(times, time.Now())
+		return errors.New("boom")
+	})
+	for i, d := range delays {
+		if got := times[i+1].Sub(times[i]); got < d {
+			t.Errorf("delay before attempt %d: got %v, want at least %v", i+2, got, d)
+		}
+	}
+}
+
+func BenchmarkFuncDelays(b *testing.B) {
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		Delay:       time.Microsecond,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	errBoom := errors.New("boom")
+	fn := func() error { return errBoom }
+	ctx := context.Background()
+	b.ReportAllocs()
+	for range b.N {
+		retry.Func(ctx, cfg, fn)
+	}
+}
@@ -0,0 +1,234 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ErrStopped is returned by [FuncStop] when the stop channel is closed.
+var ErrStopped = errors.New("retry: stopped")
+
+// ErrNilFunc is returned by [Func] and its variants when the function to
+// retry is nil.
+var ErrNilFunc = errors.New("retry: nil function")
+
+// Unrecoverable wraps err to mark it as permanent: if the function retried
+// by [Func] returns such an error, possibly wrapped further, retries stop
+// immediately regardless of what [Config.RetryOn] reports, and the original
+// err is returned to the caller.
+//
+// Unrecoverable returns nil if err is nil.
+func Unrecoverable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &unrecoverableError{err: err}
+}
+
+type unrecoverableError struct{ err error }
+
+func (e *unrecoverableError) Error() string { return e.err.Error() }
+func (e *unrecoverableError) Unwrap() error { return e.err }
+
+// asUnrecoverable reports whether err was marked with [Unrecoverable],
+// and if so, returns the original error.
+func asUnrecoverable(err error) (error, bool) {
+	if u, ok := findError[*unrecoverableError](err); ok {
+		return u.err, true
+	}
+	return err, false
+}
+
+// After wraps err to request that the next attempt is made after d: if the
+// function retried by [Func] returns such an error, possibly wrapped further,
+// and it is retried, d is used as the delay instead of the one computed from
+// Delay, the delay function, or [Config.WithErrorDelay]. MaxDelay, MinDelay
+// and jitter still apply. If err is wrapped with After more than once, the
+// outermost wrapping wins.
+//
+// The wrapped error is passed to retry predicates and returned by Func as is;
+// use [errors.Is] or [errors.As] to inspect it. After returns nil if err is
+// nil.
+func After(d time.Duration, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &afterError{d: max(0, d), err: err}
+}
+
+type afterError struct {
+	d   time.Duration
+	err error
+}
+
+func (e *afterError) Error() string { return e.err.Error() }
+func (e *afterError) Unwrap() error { return e.err }
+
+// requestedDelay returns the delay requested with [After], if any.
+func requestedDelay(err error) (time.Duration, bool) {
+	if a, ok := findError[*afterError](err); ok {
+		return a.d, true
+	}
+	return 0, false
+}
+
+// findError is like [errors.As] for the error type T, but unlike it, does
+// not allocate unless an error in the tree of err has an As method. It is
+// used on every attempt, so that retries of a function that doesn't fail
+// with such errors stay allocation-free.
+func findError[T error](err error) (T, bool) {
+	var zero T
+	for err != nil {
+		if t, ok := err.(T); ok {
+			return t, true
+		}
+		if x, ok := err.(interface{ As(any) bool }); ok {
+			var t T
+			if x.As(&t) {
+				return t, true
+			}
+		}
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, err := range x.Unwrap() {
+				if t, ok := findError[T](err); ok {
+					return t, true
+				}
+			}
+			return zero, false
+		default:
+			return zero, false
+		}
+	}
+	return zero, false
+}
+
+// ExhaustedError is returned by [Func] when it runs out of attempts or time
+// and [Config.WrapExhausted] is set. It wraps the error of the last attempt,
+// or of the first one if [Config.ReturnFirstError] is set.
+type ExhaustedError struct {
+	Attempts int   // number of attempts made
+	Err      error // error of the last (or first) attempt
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("retry: %d attempts failed: %v", e.Attempts, e.Err)
+}
+
+func (e *ExhaustedError) Unwrap() error { return e.Err }
+
+// AttemptError is an error of a single attempt, see [AttemptErrors].
+type AttemptError struct {
+	Attempt int       // attempt number, starting at 1; zero if not from an attempt
+	At      time.Time // when the attempt failed
+	Err     error     // error returned by the attempt
+}
+
+func (e AttemptError) Error() string {
+	if e.Attempt == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("attempt %d: %v", e.Attempt, e.Err)
+}
+
+func (e AttemptError) Unwrap() error { return e.Err }
+
+// AttemptErrors is returned by [Func] when [Config.CollectErrors] is set.
+// It holds the errors of all failed attempts in order. If retries stopped
+// for a reason other than the error of an attempt, the last entry holds the
+// error that stopped them, with its Attempt set to zero: the context error if
+// the context was canceled, the error returned by [Config.BeforeAttempt], or
+// [ErrCircuitOpen] if [Config.Breaker] didn't allow the next attempt.
+//
+// Both [errors.Is] and [errors.As] check all the entries.
+type AttemptErrors []AttemptError
+
+func (e AttemptErrors) Error() string {
+	var b strings.Builder
+	for i, err := range e {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (e AttemptErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// IsRetryExhausted reports whether err, as returned by [Func], means that
+// retries stopped because attempts or time ran out. It relies on
+// [Config.WrapExhausted] being set, and reports false otherwise.
+func IsRetryExhausted(err error) bool {
+	var e *ExhaustedError
+	return errors.As(err, &e)
+}
+
+// IsContextError reports whether err, as returned by [Func], means that
+// retries stopped because the context was canceled or its deadline passed,
+// or because the stop channel of [FuncStop] was closed. Custom cancellation
+// causes set with [context.WithCancelCause] are not recognized.
+func IsContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrStopped)
+}
+
+// PanicError is returned by [Func] in place of an error when the retried
+// function panics and [Config.RecoverPanics] is set.
+type PanicError struct {
+	Value any    // value passed to panic
+	Stack []byte // stack trace of the goroutine that panicked
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// Unwrap returns the value passed to panic if it is an error, or nil otherwise.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// RetryPanicsOnly returns a copy of the [Config] that retries panics of the
+// retried function, but no errors: it sets [Config.RecoverPanics], and sets
+// RetryOn to a predicate matching [*PanicError] only, so errors returned by
+// the function are passed through to the caller without retries. Since other
+// retry predicates take precedence over RetryOn, they are cleared.
+// If attempts run out, the *PanicError of the last attempt is returned.
+func (c *Config) RetryPanicsOnly() Config {
+	cfg := *c
+	cfg.RecoverPanics = true
+	cfg.RetryOn = func(err error) bool {
+		var p *PanicError
+		return errors.As(err, &p)
+	}
+	cfg.StopOn, cfg.RetryOnN, cfg.RetryOnCtx, cfg.RetryOnElapsed, cfg.Decide = nil, nil, nil, nil, nil
+	return cfg
+}
+
+// recoverPanics wraps fn so that a panic in it is returned as a *PanicError.
+func recoverPanics(fn func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = &PanicError{Value: v, Stack: debug.Stack()}
+			}
+		}()
+		return fn(ctx)
+	}
+}
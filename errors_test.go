@@ -0,0 +1,220 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestUnrecoverable(t *testing.T) {
+	errPermanent := errors.New("permanent failure")
+	var calls int
+	fn := func() error {
+		calls++
+		return fmt.Errorf("wrapped: %w", retry.Unrecoverable(errPermanent))
+	}
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		RetryOn:     func(error) bool { return true },
+	}
+	err := retry.Func(context.Background(), cfg, fn)
+	if err != errPermanent {
+		t.Fatalf("got unexpected error %v, want %v", err, errPermanent)
+	}
+	if calls != 1 {
+		t.Fatalf("expecting exactly 1 function call, got %d", calls)
+	}
+	if retry.Unrecoverable(nil) != nil {
+		t.Fatal("Unrecoverable(nil) returned non-nil error")
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	errBoom := errors.New("boom")
+	for _, v := range []any{errBoom, "boom"} {
+		t.Run(fmt.Sprintf("%T", v), func(t *testing.T) {
+			var calls int
+			fn := func() error { calls++; panic(v) }
+			cfg := retry.Config{
+				MaxAttempts:   3,
+				RetryOn:       func(err error) bool { return err != nil },
+				RecoverPanics: true,
+			}
+			err := retry.Func(context.Background(), cfg, fn)
+			var perr *retry.PanicError
+			if !errors.As(err, &perr) {
+				t.Fatalf("got unexpected error %v, want *retry.PanicError", err)
+			}
+			if perr.Value != v {
+				t.Fatalf("got panic value %v, want %v", perr.Value, v)
+			}
+			if calls != cfg.MaxAttempts {
+				t.Fatalf("got %d calls, want %d", calls, cfg.MaxAttempts)
+			}
+			if !strings.Contains(err.Error(), "TestRecoverPanics") {
+				t.Fatalf("error text does not include stack trace: %q", err)
+			}
+			if _, ok := v.(error); ok && !errors.Is(err, errBoom) {
+				t.Fatalf("errors.Is(%v, %v) is false", err, errBoom)
+			}
+		})
+	}
+	t.Run("notRetryable", func(t *testing.T) {
+		var calls int
+		fn := func() error { calls++; panic("boom") }
+		cfg := retry.Config{
+			MaxAttempts:   3,
+			RetryOn:       func(err error) bool { var p *retry.PanicError; return !errors.As(err, &p) },
+			RecoverPanics: true,
+		}
+		err := retry.Func(context.Background(), cfg, fn)
+		var perr *retry.PanicError
+		if !errors.As(err, &perr) {
+			t.Fatalf("got unexpected error %v, want *retry.PanicError", err)
+		}
+		if calls != 1 {
+			t.Fatalf("expecting exactly 1 function call, got %d", calls)
+		}
+	})
+}
+
+func TestWrapExhausted(t *testing.T) {
+	errBoom := errors.New("boom")
+	errPermanent := errors.New("permanent")
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return errors.Is(err, errBoom) },
+	}
+	// default behavior: the last error is returned as is
+	err := retry.Func(context.Background(), cfg, func() error { return errBoom })
+	if err != errBoom {
+		t.Fatalf("got unexpected error %v, want %v", err, errBoom)
+	}
+
+	cfg.WrapExhausted = true
+	err = retry.Func(context.Background(), cfg, func() error { return errBoom })
+	var exhausted *retry.ExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("got unexpected error %v, want *retry.ExhaustedError", err)
+	}
+	if exhausted.Attempts != 3 || !errors.Is(err, errBoom) {
+		t.Fatalf("got %d attempts and error %v, want 3 and %v", exhausted.Attempts, exhausted.Err, errBoom)
+	}
+
+	// an error not worth retrying is not wrapped
+	err = retry.Func(context.Background(), cfg, func() error { return errPermanent })
+	if err != errPermanent {
+		t.Fatalf("got unexpected error %v, want %v", err, errPermanent)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	errBoom := errors.New("boom")
+	retryOn := func(err error) bool { return err != nil }
+
+	exhausted := retry.Func(context.Background(), retry.Config{MaxAttempts: 2, RetryOn: retryOn, WrapExhausted: true},
+		func() error { return errBoom })
+	plain := retry.Func(context.Background(), retry.Config{MaxAttempts: 2, RetryOn: retryOn},
+		func() error { return errBoom })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := retry.Func(ctx, retry.Config{}, func() error { return nil })
+	deadline := retry.FuncTimeout(context.Background(), time.Millisecond,
+		retry.Config{MaxAttempts: 2, RetryOn: retryOn, Delay: time.Hour}, func() error { return errBoom })
+	stop := make(chan struct{})
+	close(stop)
+	stopped := retry.FuncStop(stop, retry.Config{MaxAttempts: 2, RetryOn: retryOn, Delay: time.Hour},
+		func() error { return errBoom })
+
+	for _, tc := range []struct {
+		name              string
+		err               error
+		exhausted, ctxErr bool
+	}{
+		{"exhausted", exhausted, true, false},
+		{"plain", plain, false, false},
+		{"canceled", canceled, false, true},
+		{"deadline", deadline, false, true},
+		{"stopped", stopped, false, true},
+		{"nil", nil, false, false},
+	} {
+		if got := retry.IsRetryExhausted(tc.err); got != tc.exhausted {
+			t.Errorf("%s: IsRetryExhausted(%v) = %t, want %t", tc.name, tc.err, got, tc.exhausted)
+		}
+		if got := retry.IsContextError(tc.err); got != tc.ctxErr {
+			t.Errorf("%s: IsContextError(%v) = %t, want %t", tc.name, tc.err, got, tc.ctxErr)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	if retry.After(time.Second, nil) != nil {
+		t.Fatal("After(d, nil) is not nil")
+	}
+	errBoom := errors.New("boom")
+	results := []error{
+		retry.After(time.Minute, errBoom),
+		fmt.Errorf("outer: %w", retry.After(time.Hour, retry.After(time.Minute, errBoom))),
+		errBoom,
+		nil,
+	}
+	clk := &fakeClock{auto: true}
+	var starts []time.Duration
+	var n int
+	fn := func() error {
+		starts = append(starts, clk.Now().Sub(time.Time{}))
+		n++
+		return results[n-1]
+	}
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		Delay:       time.Second,
+		RetryOn:     func(err error) bool { return errors.Is(err, errBoom) },
+	}
+	cfg = cfg.WithErrorDelay(func(error) (time.Duration, bool) { return 2 * time.Second, true })
+	if err := retry.Func(context.Background(), cfg.WithClock(clk), fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	want := []time.Duration{0, time.Minute, time.Minute + time.Hour, time.Minute + time.Hour + 2*time.Second}
+	if !slices.Equal(starts, want) {
+		t.Fatalf("got attempts started at %v, want %v", starts, want)
+	}
+}
+
+func TestConfigRetryPanicsOnly(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		StopOn:      func(error) bool { return false }, // cleared
+	}
+	cfg = cfg.RetryPanicsOnly()
+	var calls int
+	err := retry.Func(context.Background(), cfg, func() error {
+		if calls++; calls <= 2 {
+			panic("flaky")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("got error %v after %d calls, want success after 3 calls", err, calls)
+	}
+
+	errBoom := errors.New("boom")
+	calls = 0
+	err = retry.Func(context.Background(), cfg, func() error { calls++; return errBoom })
+	if err != errBoom || calls != 1 {
+		t.Fatalf("got error %v after %d calls, want %v after 1 call", err, calls, errBoom)
+	}
+
+	calls = 0
+	err = retry.Func(context.Background(), cfg, func() error { calls++; panic("always") })
+	var p *retry.PanicError
+	if !errors.As(err, &p) || calls != cfg.MaxAttempts {
+		t.Fatalf("got error %v after %d calls, want *retry.PanicError after %d calls", err, calls, cfg.MaxAttempts)
+	}
+}
@@ -0,0 +1,14 @@
+package retry
+
+import "time"
+
+// DelayFunc exposes the delay function configured on c for tests.
+func DelayFunc(c Config) func(int) time.Duration {
+	if c.newDelayFn != nil {
+		return c.newDelayFn(&c)
+	}
+	return c.delayFn
+}
+
+// Delay exposes the delay Func waits after attempt i for tests.
+func Delay(c Config, i int) time.Duration { return c.delay(i) }
@@ -0,0 +1,51 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// RetryGroup retries a group of related operations concurrently, each with
+// [Func], using the same context and [Config]. Unlike errgroup, the failure
+// of one operation doesn't cancel the others. To limit the total number of
+// retries made by the group, set [Config.Budget].
+//
+// The Config must be safe for concurrent use, see [Config.WithRand].
+type RetryGroup struct {
+	ctx context.Context
+	cfg Config
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewGroup returns a new [RetryGroup] that retries operations with ctx and
+// cfg.
+func NewGroup(ctx context.Context, cfg Config) *RetryGroup {
+	return &RetryGroup{ctx: ctx, cfg: cfg}
+}
+
+// Go calls fn in a new goroutine, retrying it as [Func] does.
+func (g *RetryGroup) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := Func(g.ctx, g.cfg, fn); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until all operations started with Go return, and then returns
+// the errors of the operations that ultimately failed, joined with
+// [errors.Join], or nil if all of them succeeded.
+func (g *RetryGroup) Wait() error {
+	g.wg.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
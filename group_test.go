@@ -0,0 +1,47 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/artyom/retry"
+)
+
+func TestRetryGroup(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	g := retry.NewGroup(context.Background(), cfg)
+	errBroken := errors.New("broken")
+	var calls [3]atomic.Int32
+	// operation i fails i times before succeeding
+	op := func(i int) func() error {
+		return func() error {
+			if n := calls[i].Add(1); int(n) <= i {
+				return errors.New("temporary")
+			}
+			return nil
+		}
+	}
+	g.Go(op(0))
+	g.Go(op(2))
+	g.Go(func() error { calls[1].Add(1); return errBroken })
+	err := g.Wait()
+	if !errors.Is(err, errBroken) {
+		t.Fatalf("got unexpected error %v, want %v", err, errBroken)
+	}
+	if got := []int32{calls[0].Load(), calls[1].Load(), calls[2].Load()}; got[0] != 1 || got[1] != 3 || got[2] != 3 {
+		t.Fatalf("got calls %v, want [1 3 3]", got)
+	}
+
+	g = retry.NewGroup(context.Background(), cfg)
+	calls = [3]atomic.Int32{}
+	g.Go(op(1))
+	g.Go(op(2))
+	if err := g.Wait(); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+}
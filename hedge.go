@@ -0,0 +1,116 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+)
+
+// ErrUnlimitedHedge is returned by [HedgedFuncVal] if [Config.Unlimited] is
+// set, as it would start an unbounded number of concurrent attempts.
+var ErrUnlimitedHedge = errors.New("retry: unlimited hedged attempts")
+
+// HedgedFuncVal calls fn, and if it doesn't return within hedgeDelay, calls
+// it again concurrently, up to the maximum number of attempts set by the
+// [Config]. An attempt that fails with a retryable error also starts the
+// next one right away. The first result that is either a success or a
+// non-retryable error is returned, and the contexts of all other attempts
+// are canceled. If all attempts fail with retryable errors, the result of
+// the last one to complete is returned.
+//
+// Delay settings of the Config are not used, as attempts are staggered by
+// hedgeDelay instead. If hedgeDelay is not positive, no attempts are started
+// on a timer, so attempts run one at a time, each starting once the previous
+// one fails with a retryable error. Since attempts may run concurrently,
+// Unlimited is not supported, and HedgedFuncVal returns [ErrUnlimitedHedge]
+// if it is set.
+//
+// Besides the retry predicates, only MaxAttempts, MaxRetries, RecoverPanics,
+// ZeroOnError, and the clock of the Config are used. Other settings, such as
+// Breaker, Budget, Metrics, BeforeAttempt, MaxSameError, and callbacks such
+// as OnRetry, are ignored.
+func HedgedFuncVal[T any](ctx context.Context, cfg Config, hedgeDelay time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	if fn == nil {
+		return zero, ErrNilFunc
+	}
+	if cfg.Unlimited {
+		return zero, ErrUnlimitedHedge
+	}
+	if err := contextErr(ctx); err != nil {
+		return zero, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		attempt int
+		val     T
+		err     error
+	}
+	results := make(chan result)
+	call := func(attempt int) {
+		var res result
+		res.attempt = attempt
+		defer func() {
+			if cfg.RecoverPanics {
+				if v := recover(); v != nil {
+					res.err = &PanicError{Value: v, Stack: debug.Stack()}
+				}
+			}
+			select {
+			case results <- res:
+			case <-ctx.Done():
+			}
+		}()
+		res.val, res.err = fn(ctx)
+	}
+
+	clk := cfg.getClock()
+	start := clk.Now()
+	maxAttempts := cfg.maxAttempts()
+	var launched, pending int
+	var timer Timer
+	launch := func() {
+		launched++
+		pending++
+		go call(launched)
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+		}
+		if launched < maxAttempts && hedgeDelay > 0 {
+			timer = clk.NewTimer(hedgeDelay)
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	launch()
+	for {
+		var hedge <-chan time.Time
+		if timer != nil {
+			hedge = timer.C()
+		}
+		select {
+		case <-ctx.Done():
+			return zero, contextErr(ctx)
+		case <-hedge:
+			launch()
+		case res := <-results:
+			pending--
+			err, stop := asUnrecoverable(res.err)
+			if stop || !cfg.decide(res.attempt, clk.Now().Sub(start), err).Retry {
+				return zeroOnError(&cfg, res.val, err), err
+			}
+			if launched < maxAttempts {
+				launch()
+			} else if pending == 0 {
+				return zeroOnError(&cfg, res.val, err), err
+			}
+		}
+	}
+}
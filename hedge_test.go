@@ -0,0 +1,115 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestHedgedFuncVal(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	t.Run("secondWins", func(t *testing.T) {
+		var calls atomic.Int32
+		canceled := make(chan struct{})
+		fn := func(ctx context.Context) (string, error) {
+			if calls.Add(1) == 1 {
+				<-ctx.Done() // first attempt hangs
+				close(canceled)
+				return "", ctx.Err()
+			}
+			return "second", nil
+		}
+		val, err := retry.HedgedFuncVal(context.Background(), cfg, 10*time.Millisecond, fn)
+		if err != nil || val != "second" {
+			t.Fatalf("got (%q, %v), want (\"second\", nil)", val, err)
+		}
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("losing attempt was not canceled")
+		}
+		if n := calls.Load(); n != 2 {
+			t.Fatalf("got %d calls, want 2", n)
+		}
+	})
+	t.Run("allFail", func(t *testing.T) {
+		var calls atomic.Int32
+		fn := func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			return "", errors.New("boom")
+		}
+		_, err := retry.HedgedFuncVal(context.Background(), cfg, time.Hour, fn)
+		if err == nil {
+			t.Fatal("expected to get error from retry.HedgedFuncVal, but got nil")
+		}
+		if n := calls.Load(); n != 3 {
+			t.Fatalf("got %d calls, want 3", n)
+		}
+	})
+	t.Run("notRetryable", func(t *testing.T) {
+		errPermanent := errors.New("permanent")
+		var calls atomic.Int32
+		fn := func(ctx context.Context) (string, error) {
+			calls.Add(1)
+			return "", retry.Unrecoverable(errPermanent)
+		}
+		_, err := retry.HedgedFuncVal(context.Background(), cfg, time.Hour, fn)
+		if err != errPermanent {
+			t.Fatalf("got unexpected error %v, want %v", err, errPermanent)
+		}
+		if n := calls.Load(); n != 1 {
+			t.Fatalf("got %d calls, want 1", n)
+		}
+	})
+	t.Run("zeroOnError", func(t *testing.T) {
+		cfg := cfg
+		cfg.ZeroOnError = true
+		fn := func(ctx context.Context) (string, error) {
+			return "partial", retry.Unrecoverable(errors.New("permanent"))
+		}
+		val, err := retry.HedgedFuncVal(context.Background(), cfg, time.Hour, fn)
+		if err == nil || val != "" {
+			t.Fatalf("got (%q, %v), want zero value and error", val, err)
+		}
+	})
+	t.Run("noHedgeDelay", func(t *testing.T) {
+		var running, peak, calls atomic.Int32
+		fn := func(ctx context.Context) (string, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			if n > peak.Load() {
+				peak.Store(n)
+			}
+			calls.Add(1)
+			time.Sleep(time.Millisecond)
+			return "", errors.New("boom")
+		}
+		if _, err := retry.HedgedFuncVal(context.Background(), cfg, 0, fn); err == nil {
+			t.Fatal("expected to get error from retry.HedgedFuncVal, but got nil")
+		}
+		if n := calls.Load(); n != 3 {
+			t.Fatalf("got %d calls, want 3", n)
+		}
+		if n := peak.Load(); n != 1 {
+			t.Fatalf("got %d concurrent calls, want 1", n)
+		}
+	})
+	t.Run("unlimited", func(t *testing.T) {
+		cfg := retry.Config{Unlimited: true, RetryOn: func(err error) bool { return err != nil }}
+		var calls atomic.Int32
+		fn := func(ctx context.Context) (string, error) { calls.Add(1); return "", nil }
+		if _, err := retry.HedgedFuncVal(context.Background(), cfg, time.Millisecond, fn); err != retry.ErrUnlimitedHedge {
+			t.Fatalf("got error %v, want %v", err, retry.ErrUnlimitedHedge)
+		}
+		if n := calls.Load(); n != 0 {
+			t.Fatalf("got %d calls, want 0", n)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryAfter returns the delay requested by the server in the Retry-After
+// header of resp. Both the delay-seconds and the HTTP-date forms of the
+// header are supported; a date in the past results in a zero delay.
+// It returns false if resp is nil, or the header is missing or malformed.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	val := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if val == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseInt(val, 10, 64); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		if secs > int64(maxDuration/time.Second) {
+			return maxDuration, true
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(val)
+	if err != nil {
+		return 0, false
+	}
+	return max(0, time.Until(t)), true
+}
+
+// WithRetryAfter is the same as [Config.WithErrorDelay]. It is meant to be
+// used with [RetryAfter] to honor the delay requested by an HTTP server,
+// see the example.
+func (c *Config) WithRetryAfter(fn func(error) (time.Duration, bool)) Config {
+	return c.WithErrorDelay(fn)
+}
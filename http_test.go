@@ -0,0 +1,93 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{header: "", ok: false},
+		{header: "120", want: 2 * time.Minute, ok: true},
+		{header: " 0 ", want: 0, ok: true},
+		{header: "-5", ok: false},
+		{header: "soon", ok: false},
+		{header: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0, ok: true},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if tc.header != "" {
+			resp.Header.Set("Retry-After", tc.header)
+		}
+		d, ok := retry.RetryAfter(resp)
+		if d != tc.want || ok != tc.ok {
+			t.Errorf("RetryAfter for header %q: got (%v, %t), want (%v, %t)",
+				tc.header, d, ok, tc.want, tc.ok)
+		}
+	}
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if d, ok := retry.RetryAfter(resp); !ok || d < 59*time.Minute || d > time.Hour {
+		t.Errorf("RetryAfter for date an hour from now: got (%v, %t)", d, ok)
+	}
+	if _, ok := retry.RetryAfter(nil); ok {
+		t.Error("RetryAfter(nil) reported ok")
+	}
+}
+
+// statusError is returned for unsuccessful HTTP responses.
+type statusError struct{ resp *http.Response }
+
+func (e *statusError) Error() string { return e.resp.Status }
+
+func ExampleConfig_WithRetryAfter() {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprintln(w, "hello")
+	}))
+	defer srv.Close()
+
+	fn := func() error {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return &statusError{resp: resp}
+		}
+		return nil
+	}
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		Delay:       time.Hour, // overridden by Retry-After
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithRetryAfter(func(err error) (time.Duration, bool) {
+		var se *statusError
+		if errors.As(err, &se) {
+			return retry.RetryAfter(se.resp)
+		}
+		return 0, false
+	})
+	err := retry.Func(context.Background(), cfg, fn)
+	fmt.Printf("requests: %d, error: %v\n", requests, err)
+	// Output:
+	// requests: 2, error: <nil>
+}
@@ -0,0 +1,112 @@
+package retry
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Limiter is a weighted semaphore limiting the number of concurrent
+// retries, see [Config.Concurrency]. A single Limiter is meant to be shared
+// by many Func calls, and is safe for concurrent use.
+type Limiter struct {
+	size int64
+
+	mu      sync.Mutex
+	cur     int64
+	waiters list.List // of limiterWaiter
+}
+
+type limiterWaiter struct {
+	n     int64
+	ready chan struct{} // closed when the weight is acquired
+}
+
+// NewLimiter returns a new [Limiter] with the given maximum combined weight
+// for concurrent access.
+func NewLimiter(n int64) *Limiter {
+	return &Limiter{size: n}
+}
+
+// Acquire acquires the limiter with a weight of n, blocking until resources
+// are available or ctx is done. On success, it returns nil. On failure,
+// it returns the cause of the context cancellation and leaves the limiter
+// unchanged.
+func (l *Limiter) Acquire(ctx context.Context, n int64) error {
+	done := ctx.Done()
+	l.mu.Lock()
+	if done != nil {
+		select {
+		case <-done:
+			l.mu.Unlock()
+			return context.Cause(ctx)
+		default:
+		}
+	}
+	if l.size-l.cur >= n && l.waiters.Len() == 0 {
+		l.cur += n
+		l.mu.Unlock()
+		return nil
+	}
+	if n > l.size {
+		// can never succeed, don't block other waiters
+		l.mu.Unlock()
+		<-done
+		return context.Cause(ctx)
+	}
+	ready := make(chan struct{})
+	elem := l.waiters.PushBack(limiterWaiter{n: n, ready: ready})
+	l.mu.Unlock()
+
+	select {
+	case <-done:
+		l.mu.Lock()
+		select {
+		case <-ready:
+			// acquired after ctx was canceled, put the weight back
+			l.cur -= n
+			l.notifyWaiters()
+		default:
+			isFront := l.waiters.Front() == elem
+			l.waiters.Remove(elem)
+			if isFront && l.size > l.cur {
+				l.notifyWaiters()
+			}
+		}
+		l.mu.Unlock()
+		return context.Cause(ctx)
+	case <-ready:
+		return nil
+	}
+}
+
+// Release releases the limiter with a weight of n.
+func (l *Limiter) Release(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cur -= n
+	if l.cur < 0 {
+		panic("retry: Limiter released more than held")
+	}
+	l.notifyWaiters()
+}
+
+// notifyWaiters wakes up waiters in FIFO order while there are enough
+// resources for them. l.mu must be held.
+func (l *Limiter) notifyWaiters() {
+	for {
+		next := l.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(limiterWaiter)
+		if l.size-l.cur < w.n {
+			// not enough resources for the next waiter; keep FIFO order
+			// so that large requests are not starved
+			return
+		}
+		l.cur += w.n
+		l.waiters.Remove(next)
+		close(w.ready)
+	}
+}
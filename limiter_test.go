@@ -0,0 +1,71 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestConfigConcurrency(t *testing.T) {
+	const limit = 2
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		Delay:       time.Millisecond,
+		RetryOn:     func(err error) bool { return err != nil },
+		Concurrency: retry.NewLimiter(limit),
+	}
+	var active, maxActive atomic.Int32
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var n int
+			_ = retry.Func(context.Background(), cfg, func() error {
+				n++
+				if n == 1 {
+					return errors.New("boom")
+				}
+				cur := active.Add(1)
+				defer active.Add(-1)
+				for {
+					old := maxActive.Load()
+					if cur <= old || maxActive.CompareAndSwap(old, cur) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return errors.New("boom")
+			})
+		}()
+	}
+	wg.Wait()
+	if n := maxActive.Load(); n > limit {
+		t.Fatalf("got %d concurrent retries, want at most %d", n, limit)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := retry.NewLimiter(2)
+	ctx := context.Background()
+	if err := l.Acquire(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got unexpected error %v, want %v", err, context.DeadlineExceeded)
+	}
+	done := make(chan error)
+	go func() { done <- l.Acquire(context.Background(), 1) }()
+	l.Release(2)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	l.Release(1)
+}
@@ -0,0 +1,129 @@
+package retry
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// LoopAttempt describes an iteration of [Loop].
+type LoopAttempt struct {
+	AttemptInfo
+	// LastErr is the error reported with SetError for the previous
+	// iteration; it is nil on the first one, and if the previous iteration
+	// reported nothing.
+	LastErr error
+
+	err      error
+	reported bool
+}
+
+// SetError reports the outcome of the attempt to the loop, as a range-over-func
+// loop body can't return a value. It is optional: if it is called, the loop
+// treats err as [Func] treats the error returned by the retried function,
+// stopping on a nil error or an error that is not retryable according to the
+// Config, and computing the next delay from it. If it is not called, the loop
+// continues until it is exhausted or the loop body breaks out of it.
+//
+// SetError must be called from the loop body, before the iteration ends.
+// Only the last call in an iteration counts.
+func (a *LoopAttempt) SetError(err error) {
+	a.err, a.reported = err, true
+}
+
+// Loop returns an iterator over attempts for writing retries as a plain loop.
+// The simplest loop reports nothing back to the iterator and breaks on
+// success:
+//
+//	for range retry.Loop(ctx, cfg) {
+//		if err = do(); err == nil {
+//			break
+//		}
+//	}
+//
+// Alternatively, the loop body may report the error of each attempt with
+// [LoopAttempt.SetError], so that the loop stops on success or on an error
+// that is not retryable, as [Func] does:
+//
+//	for a := range retry.Loop(ctx, cfg) {
+//		if a.Remaining == 0 {
+//			err = doFallback()
+//		} else {
+//			err = do()
+//		}
+//		a.SetError(err)
+//	}
+//
+// Between iterations, the iterator waits for the delay configured by cfg,
+// as Func does. It stops after the maximum number of attempts, once waiting
+// for the next delay would exceed MaxElapsedTime, or if ctx is canceled, in
+// which case it returns without waiting for the delay to end; check ctx.Err
+// after the loop to tell these cases apart. Retry predicates and other
+// settings that depend on errors are only used for errors reported with
+// SetError.
+//
+// Unlike Func, the iterator ignores callbacks, such as OnRetry, OnGiveUp,
+// OnComplete, and BeforeAttempt, as well as Metrics, Logger, and the limit
+// set with [Config.WithAttemptBudget]. Limits shared by Func calls, such as
+// Budget, Breaker, Concurrency, and Observer, are ignored too.
+func Loop(ctx context.Context, cfg Config) iter.Seq[*LoopAttempt] {
+	return func(yield func(*LoopAttempt) bool) {
+		cfg := cfg
+		if contextErr(ctx) != nil {
+			return
+		}
+		if cfg.newDelayFn != nil {
+			cfg.delayFn = cfg.newDelayFn(&cfg)
+		}
+		if cfg.sleep(ctx, cfg.InitialDelay) != nil {
+			return
+		}
+		var timer Timer // reused for all delays, created on first use
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+		clk := cfg.getClock()
+		start := clk.Now()
+		maxAttempts := cfg.maxAttempts()
+		var lastErr error
+		for attempt := 1; ; attempt++ {
+			a := &LoopAttempt{
+				AttemptInfo: AttemptInfo{
+					Attempt:     attempt,
+					MaxAttempts: maxAttempts,
+					Remaining:   max(0, maxAttempts-attempt),
+				},
+				LastErr: lastErr,
+			}
+			if !yield(a) || attempt >= maxAttempts {
+				return
+			}
+			var delay time.Duration
+			if a.reported {
+				err, stop := asUnrecoverable(a.err)
+				if err == nil || stop {
+					return
+				}
+				dec := cfg.decide(attempt, clk.Now().Sub(start), err)
+				if !dec.Retry {
+					return
+				}
+				if cfg.NoDelayOn == nil || !cfg.NoDelayOn(err) {
+					delay = cfg.adjustDelay(attempt, cfg.nextDelay(attempt, err, dec))
+				}
+				lastErr = err
+			} else {
+				lastErr = nil
+				delay = cfg.delay(attempt)
+			}
+			if cfg.MaxElapsedTime > 0 && clk.Now().Sub(start)+delay > cfg.MaxElapsedTime {
+				return
+			}
+			if cfg.sleepTimer(ctx, delay, &timer) != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestLoop(t *testing.T) {
+	clk := &fakeClock{auto: true}
+	cfg := retry.Config{MaxAttempts: 4, Delay: time.Second}
+	cfg = cfg.WithClock(clk)
+	var attempts []int
+	for a := range retry.Loop(context.Background(), cfg) {
+		attempts = append(attempts, a.Attempt)
+	}
+	if !slices.Equal(attempts, []int{1, 2, 3, 4}) {
+		t.Fatalf("got attempts %v, want [1 2 3 4]", attempts)
+	}
+	if got := clk.Now().Sub(time.Time{}); got != 3*time.Second {
+		t.Fatalf("got %v of delays, want %v", got, 3*time.Second)
+	}
+
+	t.Run("maxElapsedTime", func(t *testing.T) {
+		cfg := retry.Config{MaxAttempts: 10, Delay: time.Second, MaxElapsedTime: 2500 * time.Millisecond}
+		cfg = cfg.WithClock(&fakeClock{auto: true})
+		var n int
+		for range retry.Loop(context.Background(), cfg) {
+			n++
+		}
+		if n != 3 {
+			t.Fatalf("got %d iterations, want 3", n)
+		}
+	})
+	t.Run("break", func(t *testing.T) {
+		var n int
+		var err error
+		for range retry.Loop(context.Background(), cfg) {
+			n++
+			if err = failIf(n < 2); err == nil {
+				break
+			}
+		}
+		if err != nil || n != 2 {
+			t.Fatalf("got error %v after %d iterations, want success after 2", err, n)
+		}
+	})
+	t.Run("cancel", func(t *testing.T) {
+		cfg := retry.Config{MaxAttempts: 10, Delay: time.Hour}
+		cfg = cfg.WithClock(&fakeClock{})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var n int
+		for range retry.Loop(ctx, cfg) {
+			n++
+			time.AfterFunc(10*time.Millisecond, cancel)
+		}
+		if n != 1 || ctx.Err() == nil {
+			t.Fatalf("got %d iterations with context error %v, want 1 iteration and a canceled context", n, ctx.Err())
+		}
+		n = 0
+		for range retry.Loop(ctx, cfg) {
+			n++
+		}
+		if n != 0 {
+			t.Fatalf("got %d iterations with a canceled context, want 0", n)
+		}
+	})
+}
+
+func TestLoopSetError(t *testing.T) {
+	errTemp := errors.New("temporary")
+	errFatal := errors.New("fatal")
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		Delay:       time.Second,
+		RetryOn:     func(err error) bool { return errors.Is(err, errTemp) },
+	}
+	cfg = cfg.WithClock(&fakeClock{auto: true})
+
+	var infos []retry.AttemptInfo
+	var lastErrs []error
+	var err error
+	for a := range retry.Loop(context.Background(), cfg) {
+		infos = append(infos, a.AttemptInfo)
+		lastErrs = append(lastErrs, a.LastErr)
+		if a.Remaining > 0 {
+			err = errTemp
+		} else {
+			err = nil // the fallback used on the last attempt succeeds
+		}
+		a.SetError(err)
+	}
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	want := []retry.AttemptInfo{
+		{Attempt: 1, MaxAttempts: 4, Remaining: 3},
+		{Attempt: 2, MaxAttempts: 4, Remaining: 2},
+		{Attempt: 3, MaxAttempts: 4, Remaining: 1},
+		{Attempt: 4, MaxAttempts: 4, Remaining: 0},
+	}
+	if !slices.Equal(infos, want) {
+		t.Fatalf("got %+v, want %+v", infos, want)
+	}
+	if !slices.Equal(lastErrs, []error{nil, errTemp, errTemp, errTemp}) {
+		t.Fatalf("got last errors %v, want [<nil> temporary temporary temporary]", lastErrs)
+	}
+
+	for name, results := range map[string][]error{
+		"success":       {errTemp, nil, errTemp},
+		"notRetryable":  {errTemp, errFatal, errTemp},
+		"unrecoverable": {errTemp, retry.Unrecoverable(errTemp), errTemp},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var n int
+			for a := range retry.Loop(context.Background(), cfg) {
+				a.SetError(results[n])
+				n++
+			}
+			if n != 2 {
+				t.Fatalf("got %d iterations, want 2", n)
+			}
+		})
+	}
+}
+
+// failIf returns an error if failed is true, and nil otherwise.
+func failIf(failed bool) error {
+	if failed {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func TestLoopLastErrNotReported(t *testing.T) {
+	errs := []error{errors.New("first"), nil, errors.New("third")}
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var lastErrs []error
+	for a := range retry.Loop(context.Background(), cfg) {
+		lastErrs = append(lastErrs, a.LastErr)
+		if a.Attempt <= len(errs) && errs[a.Attempt-1] != nil {
+			a.SetError(errs[a.Attempt-1])
+		} // the second iteration reports nothing
+	}
+	if want := []error{nil, errs[0], nil, errs[2]}; !slices.Equal(lastErrs, want) {
+		t.Fatalf("got last errors %v, want %v", lastErrs, want)
+	}
+}
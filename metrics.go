@@ -0,0 +1,26 @@
+package retry
+
+import "time"
+
+// Metrics receives observations from [Func], see [Config.Metrics].
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveAttempt is called before each attempt, with the attempt number
+	// starting at 1.
+	ObserveAttempt(attempt int)
+	// ObserveDelay is called before waiting for the delay d between
+	// attempts.
+	ObserveDelay(d time.Duration)
+	// ObserveOutcome is called once per Func call, after the last attempt,
+	// reporting whether the call succeeded and how many attempts were made.
+	ObserveOutcome(success bool, totalAttempts int)
+}
+
+// NopMetrics is a [Metrics] implementation that does nothing. It can be
+// embedded into types that only need to implement some of the Metrics
+// methods.
+type NopMetrics struct{}
+
+func (NopMetrics) ObserveAttempt(int)         {}
+func (NopMetrics) ObserveDelay(time.Duration) {}
+func (NopMetrics) ObserveOutcome(bool, int)   {}
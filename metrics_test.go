@@ -0,0 +1,86 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+type countingMetrics struct {
+	mu        sync.Mutex
+	attempts  int
+	delays    []time.Duration
+	successes int
+	failures  int
+	total     int
+}
+
+func (m *countingMetrics) ObserveAttempt(int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+}
+
+func (m *countingMetrics) ObserveDelay(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.delays = append(m.delays, d)
+}
+
+func (m *countingMetrics) ObserveOutcome(success bool, totalAttempts int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if success {
+		m.successes++
+	} else {
+		m.failures++
+	}
+	m.total += totalAttempts
+}
+
+func TestConfigMetrics(t *testing.T) {
+	m := &countingMetrics{}
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		Delay:       time.Millisecond,
+		RetryOn:     func(err error) bool { return err != nil },
+		Metrics:     m,
+	}
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var n int
+			_ = retry.Func(context.Background(), cfg, func() error {
+				n++
+				if i%2 == 0 || n == 3 {
+					return nil
+				}
+				return errors.New("boom")
+			})
+		}()
+	}
+	wg.Wait()
+	// 5 calls succeed on the first attempt, and 5 on the third one
+	if m.attempts != 20 || m.total != 20 {
+		t.Errorf("got %d attempts observed, %d total, want 20", m.attempts, m.total)
+	}
+	if m.successes != 10 || m.failures != 0 {
+		t.Errorf("got %d successes and %d failures, want 10 and 0", m.successes, m.failures)
+	}
+	if len(m.delays) != 10 {
+		t.Errorf("got %d delays observed, want 10", len(m.delays))
+	}
+	for _, d := range m.delays {
+		if d != cfg.Delay {
+			t.Fatalf("got delay %v observed, want %v", d, cfg.Delay)
+		}
+	}
+
+	var _ retry.Metrics = retry.NopMetrics{}
+}
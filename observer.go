@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	observerWindow  = time.Second
+	observerBuckets = 10
+	observerBucket  = observerWindow / observerBuckets
+)
+
+// RetryObserver watches the rate of retries made by all [Func] calls sharing
+// it, and calls a function when the rate crosses a threshold, which usually
+// hints at an outage of a dependency. See [Config.Observer].
+//
+// The rate is measured over a sliding window of one second.
+//
+// RetryObserver is safe for concurrent use.
+type RetryObserver struct {
+	threshold float64
+	onTrip    func(rate float64)
+
+	mu      sync.Mutex
+	buckets [observerBuckets]struct {
+		idx   int64 // number of the bucket period since the Unix epoch
+		count int
+	}
+	tripped bool
+}
+
+// NewObserver returns a new [RetryObserver] that calls onTrip with the
+// current rate once the rate of retries reaches threshold retries per
+// second. It calls onTrip again only after the rate drops below threshold
+// and then reaches it again. onTrip is called synchronously by the Func
+// about to retry, so it should return quickly.
+func NewObserver(threshold float64, onTrip func(rate float64)) *RetryObserver {
+	return &RetryObserver{threshold: threshold, onTrip: onTrip}
+}
+
+// Rate returns the current rate of retries per second.
+func (o *RetryObserver) Rate() float64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.rate(time.Now())
+}
+
+// observe is called before each retry.
+func (o *RetryObserver) observe() {
+	now := time.Now()
+	o.mu.Lock()
+	idx := now.UnixNano() / int64(observerBucket)
+	b := &o.buckets[idx%observerBuckets]
+	if b.idx != idx {
+		b.idx, b.count = idx, 0
+	}
+	b.count++
+	rate := o.rate(now)
+	trip := rate >= o.threshold && !o.tripped
+	o.tripped = rate >= o.threshold
+	o.mu.Unlock()
+	if trip && o.onTrip != nil {
+		o.onTrip(rate)
+	}
+}
+
+// rate returns the rate of retries over the window ending at now.
+// o.mu must be held.
+func (o *RetryObserver) rate(now time.Time) float64 {
+	idx := now.UnixNano() / int64(observerBucket)
+	var n int
+	for _, b := range o.buckets {
+		if b.idx > idx-observerBuckets {
+			n += b.count
+		}
+	}
+	return float64(n) / observerWindow.Seconds()
+}
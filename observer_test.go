@@ -0,0 +1,45 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/artyom/retry"
+)
+
+func TestRetryObserver(t *testing.T) {
+	var trips []float64
+	var mu sync.Mutex
+	o := retry.NewObserver(20, func(rate float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		trips = append(trips, rate)
+	})
+	cfg := retry.Config{
+		MaxAttempts: 6,
+		RetryOn:     func(err error) bool { return err != nil },
+		Observer:    o,
+	}
+	fail := func() error { return errors.New("boom") }
+	// 4 calls making 5 retries each, well within a second
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = retry.Func(context.Background(), cfg, fail)
+		}()
+	}
+	wg.Wait()
+	if len(trips) != 1 {
+		t.Fatalf("got %d trips, want 1", len(trips))
+	}
+	if trips[0] < 20 {
+		t.Fatalf("tripped at rate %v, want at least 20", trips[0])
+	}
+	if r := o.Rate(); r < 20 {
+		t.Fatalf("got rate %v, want at least 20", r)
+	}
+}
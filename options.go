@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Option modifies a [Config]; it is used by [Do] to build a Config from
+// a list of options, and by [Config.With] to derive a modified Config.
+type Option func(*Config)
+
+// Attempts returns an [Option] that sets [Config.MaxAttempts].
+func Attempts(n int) Option { return func(c *Config) { c.MaxAttempts = n } }
+
+// FixedDelay returns an [Option] that sets [Config.Delay].
+func FixedDelay(d time.Duration) Option { return func(c *Config) { c.Delay = d } }
+
+// If returns an [Option] that sets [Config.RetryOn].
+func If(retryOn func(error) bool) Option { return func(c *Config) { c.RetryOn = retryOn } }
+
+// WithMaxDelay returns an [Option] that sets [Config.MaxDelay].
+func WithMaxDelay(d time.Duration) Option { return func(c *Config) { c.MaxDelay = d } }
+
+// WithJitter returns an [Option] that sets jitter as [Config.WithJitter]
+// does.
+func WithJitter(fraction float64) Option { return func(c *Config) { *c = c.WithJitter(fraction) } }
+
+// With returns a copy of the [Config] with opts applied to it in order.
+// The original Config is not modified.
+func (c Config) With(opts ...Option) Config {
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Do is like [Func], but takes a list of options instead of a [Config].
+// Options are applied in order to an empty Config.
+func Do(ctx context.Context, fn func() error, opts ...Option) error {
+	return Func(ctx, Config{}.With(opts...), fn)
+}
+
+// Default is the [Config] used by [DoDefault]. By default, it makes up to
+// 3 attempts, retrying any error with exponential backoff starting at
+// 100ms, with 20% jitter.
+//
+// Default may be replaced to set a policy shared by a whole program, but it
+// must be done before it is used, for example in an init function, as
+// modifying it concurrently with DoDefault calls is a data race.
+var Default = defaultConfig()
+
+func defaultConfig() Config {
+	cfg := Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithExponentialBackoff(100*time.Millisecond, 2)
+	return cfg.WithJitter(0.2)
+}
+
+// DoDefault is like [Func], but uses [Default] as the Config.
+func DoDefault(ctx context.Context, fn func() error) error {
+	return Func(ctx, Default, fn)
+}
+
+// ValOption configures [FuncValOpts].
+type ValOption[T any] func(*valOptions[T])
+
+type valOptions[T any] struct {
+	accept func(T) bool
+}
+
+// Accept returns a [ValOption] that makes [FuncValOpts] retry if fn returns
+// a value for which accept reports false, even if the error is nil.
+func Accept[T any](accept func(T) bool) ValOption[T] {
+	return func(o *valOptions[T]) { o.accept = accept }
+}
+
+// FuncValOpts is like [FuncVal], but takes options customizing what a
+// successful result is, see [Accept]. Errors are retried as reported by the
+// [Config]. If attempts run out while values are not accepted, FuncValOpts
+// returns the last value and a nil error.
+func FuncValOpts[T any](ctx context.Context, cfg Config, fn func() (T, error), opts ...ValOption[T]) (T, error) {
+	var val T
+	if fn == nil {
+		return val, ErrNilFunc
+	}
+	var o valOptions[T]
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var err error
+	wrap := func() error {
+		val, err = fn()
+		return err
+	}
+	if o.accept != nil {
+		cfg.retryResult = func() bool { return err == nil && !o.accept(val) }
+	}
+	ferr := Func(ctx, cfg, wrap)
+	return zeroOnError(&cfg, val, ferr), ferr
+}
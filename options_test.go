@@ -0,0 +1,126 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func ExampleDo() {
+	var n int
+	fn := func() error {
+		n++
+		if n < 3 {
+			fmt.Printf("attempt %d, failing\n", n)
+			return errors.New("boom")
+		}
+		fmt.Printf("attempt %d, succeeding\n", n)
+		return nil
+	}
+	err := retry.Do(context.Background(), fn,
+		retry.Attempts(5),
+		retry.FixedDelay(time.Millisecond),
+		retry.If(func(err error) bool { return err != nil }),
+	)
+	fmt.Println("error:", err)
+	// Output:
+	// attempt 1, failing
+	// attempt 2, failing
+	// attempt 3, succeeding
+	// error: <nil>
+}
+
+func TestDo(t *testing.T) {
+	var calls int
+	fn := func() error { calls++; return errors.New("boom") }
+	err := retry.Do(context.Background(), fn,
+		retry.Attempts(3),
+		retry.If(func(err error) bool { return err != nil }),
+	)
+	if err == nil {
+		t.Fatal("expected to get error from retry.Do, but got nil")
+	}
+	if calls != 3 {
+		t.Fatalf("got %d calls, want 3", calls)
+	}
+
+	calls = 0
+	if err := retry.Do(context.Background(), fn, retry.Attempts(3)); err == nil || calls != 1 {
+		t.Fatalf("got error %v after %d calls without retry predicate, want error after 1 call", err, calls)
+	}
+}
+
+func TestConfigWith(t *testing.T) {
+	base := retry.Config{MaxAttempts: 3, Delay: time.Second}
+	base = base.WithLinearBackoff(time.Second)
+	derived := base.With(retry.WithMaxDelay(2*time.Second), retry.WithJitter(0.2), retry.Attempts(5))
+	if base.MaxDelay != 0 || base.MaxAttempts != 3 || base.Jitter() != 0 {
+		t.Fatalf("original Config was modified: MaxDelay %v, MaxAttempts %d, jitter %v",
+			base.MaxDelay, base.MaxAttempts, base.Jitter())
+	}
+	if derived.MaxDelay != 2*time.Second || derived.MaxAttempts != 5 || derived.Jitter() != 0.2 {
+		t.Fatalf("got MaxDelay %v, MaxAttempts %d, jitter %v; want 2s, 5, 0.2",
+			derived.MaxDelay, derived.MaxAttempts, derived.Jitter())
+	}
+	// the delay function is kept
+	if got := derived.Schedule(4); !slices.Equal(got, []time.Duration{time.Second, 2 * time.Second, 2 * time.Second}) {
+		t.Fatalf("got schedule %v, want [1s 2s 2s]", got)
+	}
+}
+
+func TestDoDefault(t *testing.T) {
+	if err := retry.Default.Validate(); err != nil {
+		t.Fatalf("Default is invalid: %v", err)
+	}
+	saved := retry.Default
+	defer func() { retry.Default = saved }()
+	retry.Default = retry.Config{
+		MaxAttempts: 4,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var calls int
+	err := retry.DoDefault(context.Background(), func() error { calls++; return errors.New("boom") })
+	if err == nil {
+		t.Fatal("expected to get error from retry.DoDefault, but got nil")
+	}
+	if calls != 4 {
+		t.Fatalf("got %d calls, want 4", calls)
+	}
+}
+
+func TestFuncValOpts(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var n int
+	fn := func() (int, error) { n++; return n * 10, nil }
+	val, err := retry.FuncValOpts(context.Background(), cfg, fn, retry.Accept(func(v int) bool { return v >= 30 }))
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if val != 30 || n != 3 {
+		t.Fatalf("got %d after %d calls, want 30 after 3 calls", val, n)
+	}
+
+	// without options, it behaves as FuncVal
+	n = 0
+	if val, err := retry.FuncValOpts(context.Background(), cfg, fn); err != nil || val != 10 {
+		t.Fatalf("got (%d, %v), want (10, nil)", val, err)
+	}
+
+	// an error that is not retryable stops regardless of Accept
+	errFatal := errors.New("fatal")
+	cfg.RetryOn = func(err error) bool { return false }
+	n = 0
+	_, err = retry.FuncValOpts(context.Background(), cfg, func() (int, error) { n++; return 0, errFatal },
+		retry.Accept(func(int) bool { return false }))
+	if err != errFatal || n != 1 {
+		t.Fatalf("got error %v after %d calls, want %v after 1 call", err, n, errFatal)
+	}
+}
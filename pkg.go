@@ -3,22 +3,323 @@ package retry
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"time"
 )
 
 // Config configures the behavior of functions in this package.
 type Config struct {
 	// MaxAttempts specifies the maximum number of retry attempts.
-	// If not positive, it defaults to a single attempt (no retries).
+	// If not positive, it defaults to a single attempt (no retries),
+	// unless MaxRetries is set.
 	MaxAttempts int
+	// MaxRetries is an alternative to MaxAttempts that counts retries made
+	// after the first attempt, so that the total number of attempts is
+	// MaxRetries+1. Exactly one of MaxAttempts and MaxRetries should be set;
+	// if both are positive, MaxAttempts takes precedence.
+	MaxRetries int
+	// Unlimited, if set, makes Func retry until an attempt succeeds,
+	// a retry predicate stops it, or the context is canceled, ignoring
+	// MaxAttempts and MaxRetries.
+	Unlimited bool
+	// MaxSameError, if positive, stops retries once that many consecutive
+	// attempts return the same error: an error is the same as the previous
+	// one if [errors.Is] matches either of them to the other, or if their
+	// messages are equal. The last error is returned.
+	MaxSameError int
+	// ForceAllAttempts, if set, makes Func retry every failed attempt until
+	// attempts run out, regardless of what the retry predicates report, and
+	// return the error of the last attempt. Only a successful attempt, an
+	// error marked with [Unrecoverable], or the context stop retries early.
+	ForceAllAttempts bool
 	// RetryOn is a function that determines whether an error is retryable.
 	// It should return true if the error is retryable, false otherwise.
+	// Errors marked with [Unrecoverable] are never retried.
 	RetryOn func(error) bool
+	// StopOn is an alternative to RetryOn for cases where it is more natural
+	// to describe when to stop: StopOn(err) is equivalent to !RetryOn(err).
+	// Exactly one of RetryOn and StopOn should be set; if both are, RetryOn
+	// is used.
+	StopOn func(error) bool
+	// RetryOnN, if set, takes precedence over RetryOn and StopOn. It is like
+	// RetryOn, but also receives the attempt number, starting at 1, which is
+	// the number of calls made so far.
+	RetryOnN func(attempt int, err error) bool
+	// RetryOnCtx, if set, takes precedence over RetryOn, StopOn, and
+	// RetryOnN. It is like RetryOn, but also receives the details of the
+	// attempt, such as how many attempts remain.
+	RetryOnCtx func(info AttemptInfo, err error) bool
+	// RetryOnElapsed, if set, takes precedence over RetryOn, StopOn, and
+	// RetryOnN, but not over RetryOnCtx. It is like RetryOn, but also
+	// receives the time elapsed since the start of the first attempt, so
+	// that different errors can be given up on at different times. Unlike
+	// MaxElapsedTime, it does not account for the upcoming delay.
+	RetryOnElapsed func(elapsed time.Duration, err error) bool
+	// Decide, if set, takes precedence over RetryOn, StopOn, RetryOnN,
+	// RetryOnCtx, and RetryOnElapsed.
+	// It is called with the attempt number (starting at 1) and the error of
+	// that attempt, and decides whether to retry and, optionally, how long
+	// to wait before the next attempt.
+	Decide func(attempt int, err error) Decision
 	// Delay specifies a fixed delay between retry attempts.
-	// Use WithDelayFunc to implement more complex retry strategies.
+	// Use WithDelayFunc to implement more complex retry strategies;
+	// a delay function set this way overrides Delay, unless AddBaseDelay
+	// is set.
 	Delay time.Duration
+	// AddBaseDelay, if set, makes the delay between attempts the sum of
+	// Delay and the value of the delay function, so that Delay is a constant
+	// part added to a growing one. It has no effect without a delay function.
+	AddBaseDelay bool
+	// NoDelayOn, if set, is called with the error of each failed attempt that
+	// is about to be retried. If it returns true, the next attempt is made
+	// immediately, without any delay.
+	NoDelayOn func(error) bool
+	// Trigger, if set, cuts the current delay short: when a value is
+	// received from it (or it is closed), the next attempt starts right away.
+	// Unlike canceling the context, this doesn't stop retries.
+	Trigger <-chan struct{}
+	// InitialDelay, if positive, is the delay before the first attempt.
+	// It is independent of Delay and other settings that govern delays
+	// between attempts.
+	InitialDelay time.Duration
+	// MaxDelay, if positive, caps the delay between retry attempts,
+	// whether it comes from Delay or from a custom delay function.
+	// The cap is applied both before and after jitter, so it is a hard
+	// ceiling that jitter never exceeds.
+	MaxDelay time.Duration
+	// MinDelay, if positive, is the shortest delay between retry attempts:
+	// shorter computed delays are raised to it. It is applied after jitter
+	// and before the final MaxDelay cap, so it must not exceed MaxDelay.
+	// It doesn't affect attempts made without a delay due to NoDelayOn.
+	MinDelay time.Duration
+	// MaxElapsedTime, if positive, limits the total time spent retrying,
+	// measured from the start of the first attempt. Retries stop, returning
+	// the error of the last attempt, once waiting for the next delay would
+	// exceed this limit.
+	MaxElapsedTime time.Duration
+	// RecoverPanics, if set, makes Func recover from panics in the retried
+	// function, converting them to errors of the [*PanicError] type. Such
+	// errors are passed to RetryOn like any other error.
+	RecoverPanics bool
+	// JoinErrors, if set, makes Func return the errors of all failed
+	// attempts combined with [errors.Join], instead of only the error of
+	// the last attempt. If retries stop because the context is canceled,
+	// the context error is included too. If an attempt eventually
+	// succeeds, nil is returned as usual.
+	JoinErrors bool
+	// CollectErrors, if set, makes Func return the errors of all failed
+	// attempts as [AttemptErrors], recording when each attempt failed.
+	// It takes precedence over JoinErrors. If an attempt eventually
+	// succeeds, nil is returned as usual.
+	CollectErrors bool
+	// ReturnFirstError, if set, makes Func return the error of the first
+	// failed attempt instead of the last one when retries stop because
+	// attempts or time run out, for cases where later attempts fail with
+	// less informative errors than the root cause. JoinErrors and
+	// CollectErrors take precedence over it. When retries stop for other
+	// reasons, such as an error that is not retryable, the last error is
+	// returned as usual.
+	ReturnFirstError bool
+	// RetryContextErrors, if set, disables the default behavior of stopping
+	// right away when an attempt returns an error matching the error of the
+	// context passed to Func, such as [context.Canceled], once the context
+	// is done. By default, such an error is returned as is, without asking
+	// the retry predicates.
+	RetryContextErrors bool
+	// WrapContextError, if set, makes Func return the context error joined
+	// with the error of the last attempt with [errors.Join] if the context
+	// is canceled while waiting before the next attempt, so that [errors.Is]
+	// matches both. By default, only the context error is returned.
+	WrapContextError bool
+	// Budget, if set, limits the number of retries made by all Func calls
+	// sharing the same budget. When the budget is exhausted, retries stop,
+	// returning the error of the last attempt.
+	Budget *RetryBudget
+	// Observer, if set, is notified of each retry, to detect retry storms
+	// across all Func calls sharing it.
+	Observer *RetryObserver
+	// Breaker, if set, is checked before each attempt: if it is open,
+	// Func returns [ErrCircuitOpen] without making the attempt. The outcome
+	// of each attempt updates the breaker. The same breaker is usually
+	// shared by many Func calls.
+	Breaker *CircuitBreaker
+	// Concurrency, if set, limits the number of concurrent retries made by
+	// all Func calls sharing the same Limiter, which is a shared state. Before
+	// each retry, Func acquires the limiter, waiting if necessary, and holds
+	// it for the duration of the delay and the attempt that follows.
+	Concurrency *Limiter
+	// LimitFirstAttempt, if set, makes the first attempt subject to the
+	// Concurrency limiter too. By default, the first attempt bypasses it.
+	LimitFirstAttempt bool
+	// Metrics, if set, receives observations about attempts, delays and
+	// outcomes of Func calls. The same Metrics value is usually shared by
+	// many Config values and concurrent Func calls, so it must be safe for
+	// concurrent use.
+	Metrics Metrics
+	// Logger, if set, is used to log each retry at the Debug level with the
+	// attempt number, delay, and error, and the outcome of each Func call:
+	// success at the Info level, and giving up at the Warn level.
+	Logger *slog.Logger
+	// WrapExhausted, if set, makes Func wrap the error of the last attempt
+	// into [*ExhaustedError] when retries stop because MaxAttempts is
+	// reached, or MaxElapsedTime or Budget is exhausted. By default, the
+	// error is returned as is.
+	WrapExhausted bool
+	// ZeroOnError, if set, makes FuncVal and similar functions return
+	// the zero value instead of the value from the last attempt if the
+	// returned error is not nil, so that a partial result can't be used
+	// by accident.
+	ZeroOnError bool
+	// OnGiveUp, if set, is called once when Func stops without success,
+	// with the reason to give up, the number of attempts made, and the error
+	// to be returned.
+	OnGiveUp func(reason GiveUpReason, attempts int, err error)
+	// OnComplete, if set, is called exactly once at the end of each Func
+	// call, regardless of its outcome, with the summary of the call.
+	OnComplete func(Summary)
+	// OnRetry, if set, is called after each failed attempt that is about
+	// to be retried, with the attempt number (starting at 1) and the error
+	// of that attempt. It is called before waiting for the delay preceding
+	// the next attempt, and is never called after the last attempt or after
+	// a successful one.
+	OnRetry func(attempt int, err error)
+	// BeforeAttempt, if set, is called with the attempt number, starting at
+	// 1, before each attempt, including the first one, and before the delay
+	// preceding it. If it returns an error, Func stops without making the
+	// attempt and returns that error.
+	BeforeAttempt func(attempt int) error
 
 	delayFn func(int) time.Duration
+	// newDelayFn, if set, constructs a fresh delay function for each Func
+	// call, for strategies that keep state between attempts or depend on
+	// other Config settings
+	newDelayFn func(*Config) func(int) time.Duration
+	errorDelay func(error) (time.Duration, bool)
+	// retryResult, if set, reports whether to retry based on the result
+	// of the last attempt, regardless of its error
+	retryResult func() bool
+	multiplier  float64 // used by WithExponential
+	attemptCost time.Duration
+	// deadlineJitter is set by WithDeadlineAwareJitter
+	deadlineJitter bool
+	// backoff and healthyAfter are set by WithStatefulBackoff
+	backoff      *StatefulBackoff
+	healthyAfter time.Duration
+	jitter       float64
+	jitterSeed   *int64 // set by WithDeterministicJitter
+	rand         Rand
+	clock        Clock
+	// stop is set by FuncStop, and checked between attempts
+	stop <-chan struct{}
+}
+
+// Decision is a result of the [Config.Decide] function.
+type Decision struct {
+	// Retry reports whether the failed attempt should be retried.
+	Retry bool
+	// Delay, if positive, overrides the delay computed from Config.Delay
+	// or the delay function before the next attempt. MaxDelay and jitter
+	// still apply to it.
+	Delay time.Duration
+}
+
+// WithErrorDelay returns a copy of the [Config] that calls fn with the error
+// of each failed attempt that is about to be retried. If fn returns true,
+// the returned duration is used as the delay before the next attempt instead
+// of the one computed from Delay or the delay function. This allows errors
+// to carry their own suggested retry intervals.
+func (c *Config) WithErrorDelay(fn func(error) (time.Duration, bool)) Config {
+	cfg := *c
+	cfg.errorDelay = fn
+	return cfg
+}
+
+// ErrorDelay returns the function set with [Config.WithErrorDelay], or nil
+// if it is not set. It allows wrapping the function of an existing Config.
+func (c *Config) ErrorDelay() func(error) (time.Duration, bool) { return c.errorDelay }
+
+// WithAttemptBudget returns a copy of the [Config] that expects each attempt
+// to take up to perAttempt. If the context has a deadline, Func doesn't
+// start a retry that wouldn't finish before the deadline, counting the delay
+// preceding it, and returns the last error instead, as if MaxAttempts was
+// reached. The first attempt is always made.
+func (c *Config) WithAttemptBudget(perAttempt time.Duration) Config {
+	cfg := *c
+	cfg.attemptCost = perAttempt
+	return cfg
+}
+
+// DelayByError returns a function for [Config.WithErrorDelay] that looks up
+// the delay for an error in cases, matching its keys with [errors.Is], so
+// wrapped errors are matched too. Errors that match none of the keys get
+// the def delay; if def is negative, the delay computed by the Config is used
+// for them instead.
+//
+// Each lookup checks the keys one by one, so it takes time proportional to
+// the size of cases. If an error matches several keys, which one is used is
+// unspecified.
+func DelayByError(def time.Duration, cases map[error]time.Duration) func(error) (time.Duration, bool) {
+	return func(err error) (time.Duration, bool) {
+		for target, d := range cases {
+			if errors.Is(err, target) {
+				return d, true
+			}
+		}
+		return def, def >= 0
+	}
+}
+
+// AttemptInfo describes an attempt, see [Config.RetryOnCtx].
+type AttemptInfo struct {
+	// Attempt is the attempt number, starting at 1.
+	Attempt int
+	// MaxAttempts is the maximum number of attempts, as derived from
+	// MaxAttempts, MaxRetries, and Unlimited of the Config. It is
+	// [math.MaxInt] if the Config is Unlimited.
+	MaxAttempts int
+	// Remaining is the number of attempts left after this one;
+	// it is zero on the last attempt.
+	Remaining int
+}
+
+// GiveUpReason describes why [Func] stopped without success,
+// see [Config.OnGiveUp].
+type GiveUpReason int
+
+const (
+	// NotRetryable means the last error was not retryable, either as
+	// reported by the Config, or because it was marked with [Unrecoverable],
+	// or that BeforeAttempt or Breaker didn't allow the next attempt.
+	NotRetryable GiveUpReason = iota + 1
+	// Exhausted means MaxAttempts was reached, MaxElapsedTime or Budget
+	// was exhausted, or the next attempt wouldn't fit before the context
+	// deadline, see [Config.WithAttemptBudget].
+	Exhausted
+	// ContextCanceled means the context was canceled.
+	ContextCanceled
+)
+
+func (r GiveUpReason) String() string {
+	switch r {
+	case NotRetryable:
+		return "not retryable"
+	case Exhausted:
+		return "exhausted"
+	case ContextCanceled:
+		return "context canceled"
+	}
+	return fmt.Sprintf("GiveUpReason(%d)", int(r))
+}
+
+// Summary describes a completed [Func] call; see [Config.OnComplete].
+type Summary struct {
+	Attempts     int           // number of attempts made
+	TotalDelay   time.Duration // total time spent waiting between attempts, including InitialDelay
+	TotalElapsed time.Duration // total duration of the call
+	Err          error         // error returned by the call
 }
 
 // WithDelayFunc returns a copy of the [Config] with a custom delay function.
@@ -30,67 +331,694 @@ type Config struct {
 func (c *Config) WithDelayFunc(fn func(int) time.Duration) Config {
 	cfg := *c
 	cfg.delayFn = fn
+	cfg.newDelayFn = nil
+	cfg.backoff = nil
 	return cfg
 }
 
+// Schedule returns the delays [Func] would wait before attempts 2 through n,
+// so the result has n-1 elements, and is empty for n < 2. It allows
+// reviewing a backoff curve without calling Func.
+//
+// For the result to be deterministic, jitter set with [Config.WithJitter]
+// is not applied. Backoff strategies that are random by nature still use
+// the source of randomness of the Config, which can be seeded with
+// [Config.WithRand].
+func (c *Config) Schedule(n int) []time.Duration {
+	if n < 2 {
+		return nil
+	}
+	cfg := *c
+	cfg.jitter = 0
+	if cfg.newDelayFn != nil {
+		cfg.delayFn = cfg.newDelayFn(&cfg)
+	}
+	out := make([]time.Duration, n-1)
+	for i := range out {
+		out[i] = cfg.delay(i + 1)
+	}
+	return out
+}
+
+// ScheduleBounds is like [Config.Schedule], but reports the range of each
+// delay when jitter set with [Config.WithJitter] is applied: lo holds the
+// shortest possible delays, and hi the longest ones. Without jitter, both
+// are equal to the result of Schedule.
+func (c *Config) ScheduleBounds(n int) (lo, hi []time.Duration) {
+	sched := c.Schedule(n)
+	if sched == nil {
+		return nil, nil
+	}
+	lo = make([]time.Duration, len(sched))
+	hi = make([]time.Duration, len(sched))
+	for i, d := range sched {
+		lo[i], hi[i] = d, d
+		if c.jitter <= 0 || d <= 0 {
+			continue
+		}
+		span := float64(d) * c.jitter
+		lo[i] = max(time.Duration(max(0, float64(d)-span)), c.MinDelay)
+		hi[i] = max(time.Duration(min(float64(maxDuration), float64(d)+span)), c.MinDelay)
+		if c.MaxDelay > 0 {
+			lo[i] = min(lo[i], c.MaxDelay)
+			hi[i] = min(hi[i], c.MaxDelay)
+		}
+	}
+	return lo, hi
+}
+
+// maxAttempts returns the maximum number of attempts, which is at least 1.
+func (c *Config) maxAttempts() int {
+	if c.Unlimited {
+		return math.MaxInt
+	}
+	if c.MaxAttempts < 1 && c.MaxRetries > 0 {
+		return c.MaxRetries + 1
+	}
+	return max(1, c.MaxAttempts)
+}
+
+// DelayForAttempt returns the delay [Func] waits before attempt i (starting
+// at 1), taking into account Delay, MaxDelay, and the delay function. The
+// delay before attempt i follows the attempt i-1, so it is zero for i <= 1.
+//
+// It allows inspecting a backoff curve without calling Func. Note that with
+// jitter or randomized backoff strategies, the result is random too.
+func (c *Config) DelayForAttempt(i int) time.Duration {
+	if i <= 1 {
+		return 0
+	}
+	cfg := *c
+	if cfg.newDelayFn != nil {
+		cfg.delayFn = cfg.newDelayFn(&cfg)
+		// stateful delay functions depend on the preceding calls
+		for j := 1; j < i-1; j++ {
+			cfg.delayFn(j)
+		}
+	}
+	return cfg.delay(i - 1)
+}
+
+// delay returns the delay to wait after attempt i (starting at 1),
+// before the next attempt.
+func (c *Config) delay(i int) time.Duration {
+	return c.adjustDelay(i, c.baseDelay(i))
+}
+
+// baseDelay returns the delay after attempt i as computed from Delay
+// or the delay function.
+func (c *Config) baseDelay(i int) time.Duration {
+	if c.delayFn == nil {
+		return c.Delay
+	}
+	d := max(0, c.delayFn(i))
+	if c.AddBaseDelay && c.Delay > 0 {
+		d = min(maxDuration-c.Delay, d) + c.Delay
+	}
+	return d
+}
+
+// nextDelay returns the delay after attempt i that failed with err,
+// before MaxDelay or jitter are applied. The delay set in the decision dec
+// takes precedence, followed by the one requested with After, the delay
+// reported by the function set with WithErrorDelay, and then by the one
+// computed from Delay or the delay function.
+func (c *Config) nextDelay(i int, err error, dec Decision) time.Duration {
+	if dec.Delay > 0 {
+		return dec.Delay
+	}
+	if d, ok := requestedDelay(err); ok {
+		return d
+	}
+	if c.errorDelay != nil {
+		if d, ok := c.errorDelay(err); ok {
+			return d
+		}
+	}
+	return c.baseDelay(i)
+}
+
+// adjustDelay applies MaxDelay, jitter and MinDelay to the delay d after
+// attempt i: it caps d at MaxDelay, applies jitter, raises the result to
+// MinDelay, and then caps it again, so that MaxDelay is a hard ceiling.
+func (c *Config) adjustDelay(i int, d time.Duration) time.Duration {
+	if c.MaxDelay > 0 {
+		d = min(d, c.MaxDelay)
+	}
+	d = max(c.applyJitter(i, d), c.MinDelay)
+	if c.MaxDelay > 0 {
+		d = min(d, c.MaxDelay)
+	}
+	return d
+}
+
+// decide reports whether to retry after the attempt that returned err,
+// elapsed after the start of the first attempt.
+func (c *Config) decide(attempt int, elapsed time.Duration, err error) Decision {
+	var dec Decision
+	switch {
+	case c.Decide != nil:
+		dec = c.Decide(attempt, err)
+	case c.RetryOnCtx != nil:
+		n := c.maxAttempts()
+		info := AttemptInfo{Attempt: attempt, MaxAttempts: n, Remaining: max(0, n-attempt)}
+		dec.Retry = c.RetryOnCtx(info, err)
+	case c.RetryOnElapsed != nil:
+		dec.Retry = c.RetryOnElapsed(elapsed, err)
+	case c.RetryOnN != nil:
+		dec.Retry = c.RetryOnN(attempt, err)
+	case c.RetryOn != nil:
+		dec.Retry = c.RetryOn(err)
+	case c.StopOn != nil:
+		dec.Retry = !c.StopOn(err)
+	}
+	if !dec.Retry && c.retryResult != nil {
+		dec.Retry = c.retryResult()
+	}
+	return dec
+}
+
 // Func retries the provided function according to the [Config].
 // It returns the error from the last attempt, or nil on success.
 // The provided context can be used to cancel retries early.
 //
-// If the context is canceled, function returns an error returned
-// by the Context.Err method.
+// If the context is canceled, function returns the cancellation cause
+// as reported by [context.Cause], which is the error returned by the
+// Context.Err method unless a cause was set. If the context is already
+// canceled when Func is called, fn is not called at all. If the context
+// deadline would pass before the delay preceding the next attempt elapses,
+// Func returns [context.DeadlineExceeded] right away instead of waiting.
+//
+// If fn is nil, Func returns [ErrNilFunc].
 func Func(ctx context.Context, cfg Config, fn func() error) error {
-	if cfg.RetryOn == nil || cfg.MaxAttempts < 1 {
-		return fn()
+	if fn == nil {
+		return ErrNilFunc
 	}
-	var err error
-retryLoop:
-	for i := range cfg.MaxAttempts {
-		if i != 0 {
-			if cfg.Delay > 0 || cfg.delayFn != nil {
-				delay := cfg.Delay
-				if cfg.delayFn != nil {
-					delay = max(0, cfg.delayFn(i))
-				}
-				timer := time.NewTimer(delay)
-				select {
-				case <-ctx.Done():
-					timer.Stop()
-					err = ctx.Err()
-					break retryLoop
-				case <-timer.C:
-				}
+	_, err := run(ctx, cfg, ignoreContext(fn))
+	return err
+}
+
+// FuncCtx is like [Func], but passes ctx to fn on each attempt,
+// so that fn can abort early if ctx is canceled. The context passed to fn
+// also carries the attempt number, see [AttemptFromContext].
+func FuncCtx(ctx context.Context, cfg Config, fn func(context.Context) error) error {
+	if fn == nil {
+		return ErrNilFunc
+	}
+	var attempt int
+	wrap := func(ctx context.Context) error {
+		attempt++
+		return fn(context.WithValue(ctx, attemptKey{}, attempt))
+	}
+	_, err := run(ctx, cfg, wrap)
+	return err
+}
+
+type attemptKey struct{}
+
+// AttemptFromContext returns the attempt number, starting at 1, from the
+// context passed to the function retried by [FuncCtx] or [FuncValCtx].
+// It returns false if ctx doesn't carry the attempt number.
+func AttemptFromContext(ctx context.Context) (int, bool) {
+	n, ok := ctx.Value(attemptKey{}).(int)
+	return n, ok
+}
+
+// FuncStop is like [Func], but instead of a context, it watches the stop
+// channel: if it is closed, retries stop and FuncStop returns [ErrStopped].
+// The channel is checked before each attempt, so if it is already closed,
+// fn is not called at all.
+func FuncStop(stop <-chan struct{}, cfg Config, fn func() error) error {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	go func() {
+		select {
+		case <-stop:
+			cancel(ErrStopped)
+		case <-ctx.Done():
+		}
+	}()
+	cfg.stop = stop
+	return Func(ctx, cfg, fn)
+}
+
+// FuncTimeout is like [Func], but stops retrying once timeout elapses,
+// returning [context.DeadlineExceeded] in that case. [Config.MaxAttempts]
+// still applies, so FuncTimeout may return earlier than that.
+func FuncTimeout(parent context.Context, timeout time.Duration, cfg Config, fn func() error) error {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	return Func(ctx, cfg, fn)
+}
+
+// FuncAttempts is like [Func], but also reports how many times fn was called.
+// The number of attempts is zero if the context was canceled before the
+// first call.
+func FuncAttempts(ctx context.Context, cfg Config, fn func() error) (int, error) {
+	if fn == nil {
+		return 0, ErrNilFunc
+	}
+	return run(ctx, cfg, ignoreContext(fn))
+}
+
+func ignoreContext(fn func() error) func(context.Context) error {
+	return func(context.Context) error { return fn() }
+}
+
+func run(ctx context.Context, cfg Config, fn func(context.Context) error) (int, error) {
+	clk := cfg.getClock()
+	start := clk.Now()
+	var slept time.Duration // total time spent waiting between attempts
+	var timer Timer         // reused for all delays, created on first use
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	wait := func(d time.Duration) error {
+		var err error
+		if d <= 0 {
+			err = cfg.sleep(ctx, d)
+		} else {
+			t := clk.Now()
+			err = cfg.sleepTimer(ctx, d, &timer)
+			slept += clk.Now().Sub(t)
+		}
+		if err == nil && cfg.stop != nil {
+			// without a delay, the goroutine of FuncStop canceling ctx
+			// may not get a chance to run before the next attempt
+			select {
+			case <-cfg.stop:
+				err = ErrStopped
+			default:
+			}
+		}
+		return err
+	}
+	var held bool // whether the Concurrency limiter is acquired
+	acquire := func() error {
+		if cfg.Concurrency == nil {
+			return nil
+		}
+		if err := cfg.Concurrency.Acquire(ctx, 1); err != nil {
+			return err
+		}
+		held = true
+		return nil
+	}
+	release := func() {
+		if held {
+			cfg.Concurrency.Release(1)
+			held = false
+		}
+	}
+	defer release()
+	var errs []error            // errors of failed attempts, if JoinErrors is set
+	var collected AttemptErrors // errors of failed attempts, if CollectErrors is set
+	var firstErr error          // error of the first failed attempt, if ReturnFirstError is set
+	// record keeps err for JoinErrors, CollectErrors or ReturnFirstError;
+	// attempt is zero for errors that don't come from an attempt
+	record := func(attempt int, err error) {
+		if cfg.ReturnFirstError && firstErr == nil && attempt > 0 {
+			firstErr = err
+		}
+		switch {
+		case err == nil:
+		case cfg.CollectErrors:
+			collected = append(collected, AttemptError{Attempt: attempt, At: clk.Now(), Err: err})
+		case cfg.JoinErrors:
+			errs = append(errs, err)
+		}
+	}
+	finish := func(attempts int, err error, reason GiveUpReason) (int, error) {
+		switch {
+		case err == nil:
+		case len(collected) != 0:
+			err = collected
+		case len(errs) != 0:
+			err = errors.Join(errs...)
+		case reason == Exhausted && firstErr != nil:
+			err = firstErr
+		}
+		if reason == Exhausted && cfg.WrapExhausted && err != nil {
+			err = &ExhaustedError{Attempts: attempts, Err: err}
+		}
+		if cfg.Metrics != nil {
+			cfg.Metrics.ObserveOutcome(err == nil, attempts)
+		}
+		if cfg.Logger != nil {
+			if err == nil {
+				cfg.Logger.LogAttrs(ctx, slog.LevelInfo, "retry succeeded", slog.Int("attempts", attempts))
 			} else {
-				select {
-				case <-ctx.Done():
-					err = ctx.Err()
-					break retryLoop
-				default:
-				}
+				cfg.Logger.LogAttrs(ctx, slog.LevelWarn, "retry gave up", slog.Int("attempts", attempts),
+					slog.String("reason", reason.String()), slog.Any("err", err))
 			}
 		}
-		err = fn()
-		if cfg.RetryOn(err) {
-			continue
+		if cfg.OnGiveUp != nil && err != nil {
+			cfg.OnGiveUp(reason, attempts, err)
+		}
+		if cfg.OnComplete != nil {
+			cfg.OnComplete(Summary{
+				Attempts:     attempts,
+				TotalDelay:   slept,
+				TotalElapsed: clk.Now().Sub(start),
+				Err:          err,
+			})
+		}
+		return attempts, err
+	}
+	if err := contextErr(ctx); err != nil {
+		return finish(0, err, ContextCanceled)
+	}
+	if cfg.newDelayFn != nil {
+		// the delay function may keep the pointer it is given, so pass it a
+		// copy to keep cfg from escaping to the heap
+		c := cfg
+		cfg.delayFn = cfg.newDelayFn(&c)
+	}
+	if cfg.RecoverPanics {
+		fn = recoverPanics(fn)
+	}
+	if cfg.Breaker != nil {
+		fn = cfg.Breaker.wrap(fn)
+	}
+	maxAttempts := cfg.maxAttempts()
+	if cfg.BeforeAttempt != nil {
+		if err := cfg.BeforeAttempt(1); err != nil {
+			record(0, err)
+			return finish(0, err, NotRetryable)
+		}
+	}
+	if err := wait(cfg.InitialDelay); err != nil {
+		return finish(0, err, ContextCanceled)
+	}
+	if cfg.LimitFirstAttempt {
+		if err := acquire(); err != nil {
+			return finish(0, err, ContextCanceled)
+		}
+	}
+	if cfg.Budget != nil {
+		cfg.Budget.deposit()
+	}
+	begin := clk.Now()
+	var prevErr error // error of the previous attempt, if MaxSameError is set
+	var sameErrs int  // number of consecutive attempts that returned prevErr
+	for attempt := 1; ; attempt++ {
+		if cfg.Breaker != nil && !cfg.Breaker.allow() {
+			release()
+			record(0, ErrCircuitOpen)
+			return finish(attempt-1, ErrCircuitOpen, NotRetryable)
+		}
+		if cfg.Metrics != nil {
+			cfg.Metrics.ObserveAttempt(attempt)
+		}
+		attemptStart := clk.Now()
+		err, stop := asUnrecoverable(fn(ctx))
+		release()
+		if cfg.backoff != nil && clk.Now().Sub(attemptStart) >= cfg.healthyAfter {
+			cfg.backoff.Reset()
+		}
+		record(attempt, err)
+		if stop {
+			return finish(attempt, err, NotRetryable)
+		}
+		if !cfg.RetryContextErrors && err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+			return finish(attempt, err, ContextCanceled)
+		}
+		if cfg.MaxSameError > 0 && err != nil {
+			if prevErr != nil && (errors.Is(err, prevErr) || errors.Is(prevErr, err) ||
+				err.Error() == prevErr.Error()) {
+				sameErrs++
+			} else {
+				sameErrs = 1
+			}
+			prevErr = err
+			if sameErrs >= cfg.MaxSameError {
+				return finish(attempt, err, NotRetryable)
+			}
+		}
+		dec := cfg.decide(attempt, clk.Now().Sub(begin), err)
+		if cfg.ForceAllAttempts && err != nil {
+			dec.Retry = true
+		}
+		if !dec.Retry {
+			return finish(attempt, err, NotRetryable)
+		}
+		if attempt >= maxAttempts {
+			return finish(attempt, err, Exhausted)
+		}
+		var delay time.Duration
+		if cfg.NoDelayOn == nil || !cfg.NoDelayOn(err) {
+			delay = cfg.adjustDelay(attempt, cfg.nextDelay(attempt, err, dec))
+			if cfg.deadlineJitter {
+				delay = cfg.deadlineDelay(ctx, clk.Now(), maxAttempts-attempt, delay)
+			}
+		}
+		if cfg.MaxElapsedTime > 0 && clk.Now().Sub(begin)+delay > cfg.MaxElapsedTime {
+			return finish(attempt, err, Exhausted)
+		}
+		if cfg.attemptCost > 0 {
+			if deadline, ok := ctx.Deadline(); ok && deadline.Sub(clk.Now()) < delay+cfg.attemptCost {
+				return finish(attempt, err, Exhausted)
+			}
+		}
+		if cfg.Budget != nil && !cfg.Budget.withdraw() {
+			return finish(attempt, err, Exhausted)
+		}
+		if cfg.BeforeAttempt != nil {
+			if berr := cfg.BeforeAttempt(attempt + 1); berr != nil {
+				record(0, berr)
+				return finish(attempt, berr, NotRetryable)
+			}
+		}
+		if cfg.OnRetry != nil {
+			cfg.OnRetry(attempt, err)
+		}
+		if cfg.Observer != nil {
+			cfg.Observer.observe()
+		}
+		if cfg.Logger != nil {
+			cfg.Logger.LogAttrs(ctx, slog.LevelDebug, "retrying", slog.Int("attempt", attempt),
+				slog.Duration("delay", delay), slog.Any("err", err))
+		}
+		if cfg.Metrics != nil {
+			cfg.Metrics.ObserveDelay(delay)
+		}
+		werr := acquire()
+		if werr == nil {
+			werr = wait(delay)
+		}
+		if werr != nil {
+			record(0, werr)
+			if cfg.WrapContextError {
+				werr = errors.Join(werr, err)
+			}
+			return finish(attempt, werr, ContextCanceled)
 		}
-		break
 	}
-	return err
 }
 
 // FuncVal retries the provided function according to the [Config].
 // It returns the function result and error from the last attempt.
 // The provided context can be used to cancel retries early.
 //
-// If the context is canceled, function returns an error returned
-// by the Context.Err method.
+// If the context is canceled, function returns the cancellation cause
+// as reported by [context.Cause].
+//
+// If the returned error is not nil, the returned value is the one from the
+// last attempt, unless [Config.ZeroOnError] is set.
 func FuncVal[T any](ctx context.Context, cfg Config, fn func() (T, error)) (T, error) {
 	var val T
+	if fn == nil {
+		return val, ErrNilFunc
+	}
+	wrap := func() error {
+		var err error
+		val, err = fn()
+		return err
+	}
+	err := Func(ctx, cfg, wrap)
+	return zeroOnError(&cfg, val, err), err
+}
+
+// FuncValN is like [FuncVal], but also returns the number of the attempt,
+// starting at 1, on which it finished, whether successfully or not. The
+// attempt is zero if the context was canceled before the first call.
+func FuncValN[T any](ctx context.Context, cfg Config, fn func() (T, error)) (T, int, error) {
+	var val T
+	if fn == nil {
+		return val, 0, ErrNilFunc
+	}
+	wrap := func() error {
+		var err error
+		val, err = fn()
+		return err
+	}
+	n, err := FuncAttempts(ctx, cfg, wrap)
+	return zeroOnError(&cfg, val, err), n, err
+}
+
+// FuncVal2 is like [FuncVal], but for functions returning two values
+// and an error.
+func FuncVal2[T, U any](ctx context.Context, cfg Config, fn func() (T, U, error)) (T, U, error) {
+	var v1 T
+	var v2 U
+	if fn == nil {
+		return v1, v2, ErrNilFunc
+	}
+	wrap := func() error {
+		var err error
+		v1, v2, err = fn()
+		return err
+	}
+	err := Func(ctx, cfg, wrap)
+	return zeroOnError(&cfg, v1, err), zeroOnError(&cfg, v2, err), err
+}
+
+// FuncValCtx is like [FuncVal], but passes ctx to fn on each attempt,
+// so that fn can abort early if ctx is canceled.
+func FuncValCtx[T any](ctx context.Context, cfg Config, fn func(context.Context) (T, error)) (T, error) {
+	var val T
+	if fn == nil {
+		return val, ErrNilFunc
+	}
+	wrap := func(ctx context.Context) error {
+		var err error
+		val, err = fn(ctx)
+		return err
+	}
+	err := FuncCtx(ctx, cfg, wrap)
+	return zeroOnError(&cfg, val, err), err
+}
+
+// FuncValCtxTimeout is like [FuncValCtx], but limits each attempt to
+// attemptTimeout: the context passed to fn is canceled once it elapses.
+// The error fn returns in that case, usually [context.DeadlineExceeded],
+// is then checked by the retry predicates as any other error, while ctx
+// still bounds the whole operation.
+func FuncValCtxTimeout[T any](ctx context.Context, cfg Config, attemptTimeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	if fn == nil {
+		var val T
+		return val, ErrNilFunc
+	}
+	return FuncValCtx(ctx, cfg, func(ctx context.Context) (T, error) {
+		ctx, cancel := context.WithTimeout(ctx, attemptTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+// FuncArg is like [FuncVal], but calls fn with an argument produced by argFor
+// for each attempt, starting at 1, such as a new idempotency key or the next
+// endpoint to try.
+func FuncArg[A, T any](ctx context.Context, cfg Config, argFor func(attempt int) A, fn func(A) (T, error)) (T, error) {
+	var val T
+	if fn == nil || argFor == nil {
+		return val, ErrNilFunc
+	}
+	var attempt int
+	wrap := func() error {
+		attempt++
+		var err error
+		val, err = fn(argFor(attempt))
+		return err
+	}
+	err := Func(ctx, cfg, wrap)
+	return zeroOnError(&cfg, val, err), err
+}
+
+// FuncValRetryable is like [FuncVal], but also retries if retryVal reports
+// true for the value returned by fn, even if the error is nil. It stops
+// when neither [Config.RetryOn] nor retryVal ask to retry. If retryVal is
+// nil, values are never retried, as with FuncVal.
+func FuncValRetryable[T any](ctx context.Context, cfg Config, fn func() (T, error), retryVal func(T) bool) (T, error) {
+	var val T
+	if fn == nil {
+		return val, ErrNilFunc
+	}
 	wrap := func() error {
 		var err error
 		val, err = fn()
 		return err
 	}
+	if retryVal != nil {
+		cfg.retryResult = func() bool { return retryVal(val) }
+	}
 	err := Func(ctx, cfg, wrap)
-	return val, err
+	return zeroOnError(&cfg, val, err), err
+}
+
+// FuncValTrace is like [FuncVal], but returns the values returned by fn on
+// all attempts, in order, including the last one. This helps to see how
+// the results converged. The number of values never exceeds the maximum
+// number of attempts.
+func FuncValTrace[T any](ctx context.Context, cfg Config, fn func() (T, error)) ([]T, error) {
+	if fn == nil {
+		return nil, ErrNilFunc
+	}
+	var vals []T
+	wrap := func() error {
+		val, err := fn()
+		vals = append(vals, val)
+		return err
+	}
+	err := Func(ctx, cfg, wrap)
+	return vals, err
+}
+
+// FuncUntil is like [FuncVal], but also retries if fn returns the zero value
+// of T with a nil error, which makes it suitable for polling until something
+// becomes ready. Errors are retried as reported by the [Config].
+//
+// If attempts run out while fn keeps returning the zero value, FuncUntil
+// returns the zero value and a nil error.
+func FuncUntil[T comparable](ctx context.Context, cfg Config, fn func() (T, error)) (T, error) {
+	var val, zero T
+	if fn == nil {
+		return val, ErrNilFunc
+	}
+	var err error
+	wrap := func() error {
+		val, err = fn()
+		return err
+	}
+	cfg.retryResult = func() bool { return err == nil && val == zero }
+	ferr := Func(ctx, cfg, wrap)
+	return zeroOnError(&cfg, val, ferr), ferr
+}
+
+// FuncEach calls fn for each of the items in order, retrying each of them
+// independently according to the [Config], as [FuncCtx] does. It returns
+// a slice of errors of the same length as items, where each element is the
+// error returned by FuncCtx for the corresponding item, or nil if it
+// eventually succeeded.
+//
+// If the context is canceled, FuncEach stops early: the item being retried
+// and all the remaining ones get the cancellation cause as their errors.
+func FuncEach[T any](ctx context.Context, cfg Config, items []T, fn func(context.Context, T) error) []error {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		if err := contextErr(ctx); err != nil {
+			for j := i; j < len(errs); j++ {
+				errs[j] = err
+			}
+			break
+		}
+		if fn == nil {
+			errs[i] = ErrNilFunc
+			continue
+		}
+		errs[i] = FuncCtx(ctx, cfg, func(ctx context.Context) error { return fn(ctx, item) })
+	}
+	return errs
+}
+
+// zeroOnError returns the zero value of T if err is not nil and
+// the ZeroOnError option of cfg is set, and val otherwise.
+func zeroOnError[T any](cfg *Config, val T, err error) T {
+	if err != nil && cfg.ZeroOnError {
+		var zero T
+		return zero
+	}
+	return val
 }
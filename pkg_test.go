@@ -4,10 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/artyom/retry"
+	"github.com/artyom/retry/retrytest"
 )
 
 func ExampleFuncVal() {
@@ -79,18 +87,19 @@ func TestFunc(t *testing.T) {
 			Delay:       5 * time.Millisecond,
 			RetryOn:     func(err error) bool { return err != nil },
 		}
-		begin := time.Now()
+		clk := &fakeClock{auto: true}
+		begin := clk.Now()
 		var i int
 		fn := func() error {
-			runDelays[i] = time.Since(begin).Round(cfg.Delay)
+			runDelays[i] = clk.Now().Sub(begin)
 			i++
 			return errors.New("boom")
 		}
-		err := retry.Func(context.Background(), cfg, fn)
+		err := retry.Func(context.Background(), cfg.WithClock(clk), fn)
 		if err == nil {
 			t.Fatal("expected to get error from retry.Func, but got nil")
 		}
-		if d := time.Since(begin).Round(cfg.Delay); d != 10*time.Millisecond {
+		if d := clk.Now().Sub(begin); d != 10*time.Millisecond {
 			t.Fatalf("total time took %v, instead of 10ms", d)
 		}
 		want := [3]time.Duration{0, 5 * time.Millisecond, 10 * time.Millisecond}
@@ -125,6 +134,80 @@ func TestFunc(t *testing.T) {
 			t.Fatalf("got unexpected error %v, want %v", err, context.Canceled)
 		}
 	})
+	t.Run("deadline+delay", func(t *testing.T) {
+		var calls int
+		fn := func() error { calls++; return errors.New("boom") }
+		cfg := retry.Config{
+			MaxAttempts: 10,
+			RetryOn:     func(err error) bool { return err != nil },
+			Delay:       time.Hour,
+		}
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		err := retry.Func(ctx, cfg, fn)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got unexpected error %v, want %v", err, context.DeadlineExceeded)
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		begin := time.Now()
+		err = retry.Func(ctx, cfg, fn)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got unexpected error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if d := time.Since(begin); d > time.Second {
+			t.Fatalf("retry.Func took %v, expected to return without waiting", d)
+		}
+		if calls != 1 {
+			t.Fatalf("expecting exactly 1 function call, got %d", calls)
+		}
+	})
+	t.Run("cancelCause", func(t *testing.T) {
+		errShutdown := errors.New("shutting down")
+		fn := func() error { return errors.New("boom") }
+		cfg := retry.Config{
+			MaxAttempts: 10,
+			RetryOn:     func(err error) bool { return err != nil },
+			Delay:       time.Hour,
+		}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		time.AfterFunc(10*time.Millisecond, func() { cancel(errShutdown) })
+		err := retry.Func(ctx, cfg, fn)
+		if !errors.Is(err, errShutdown) {
+			t.Fatalf("got unexpected error %v, want %v", err, errShutdown)
+		}
+		err = retry.Func(ctx, cfg, fn)
+		if !errors.Is(err, errShutdown) {
+			t.Fatalf("got unexpected error %v, want %v", err, errShutdown)
+		}
+	})
+	t.Run("initialDelay", func(t *testing.T) {
+		cfg := retry.Config{InitialDelay: 10 * time.Millisecond}
+		begin := time.Now()
+		var started time.Duration
+		err := retry.Func(context.Background(), cfg, func() error {
+			started = time.Since(begin)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+		if started < cfg.InitialDelay {
+			t.Fatalf("first call started after %v, want at least %v", started, cfg.InitialDelay)
+		}
+	})
+	t.Run("cancel+initialDelay", func(t *testing.T) {
+		cfg := retry.Config{InitialDelay: time.Hour}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(10*time.Millisecond, cancel)
+		n, err := retry.FuncAttempts(ctx, cfg, func() error { return nil })
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got unexpected error %v, want %v", err, context.Canceled)
+		}
+		if n != 0 {
+			t.Fatalf("got %d attempts, want 0", n)
+		}
+	})
 	t.Run("emptyConfig", func(t *testing.T) {
 		var numCalls int
 		fn := func() error { numCalls++; return errors.New("boom") }
@@ -162,3 +245,1452 @@ func ExampleConfig_WithDelayFunc() {
 	// delayFunc called with argument 2
 	// error: always failing
 }
+
+func TestFuncMaxDelay(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 2,
+		MaxDelay:    5 * time.Millisecond,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithDelayFunc(func(int) time.Duration { return time.Hour })
+	begin := time.Now()
+	err := retry.Func(context.Background(), cfg, func() error { return errors.New("boom") })
+	if err == nil {
+		t.Fatal("expected to get error from retry.Func, but got nil")
+	}
+	if d := time.Since(begin); d > time.Second {
+		t.Fatalf("total time took %v, delay is not capped by MaxDelay", d)
+	}
+}
+
+func TestFuncMinDelay(t *testing.T) {
+	cfg := retry.Config{
+		MinDelay: time.Second,
+		MaxDelay: 3 * time.Second,
+	}
+	cfg = cfg.WithLinearBackoff(time.Millisecond)
+	if got, want := cfg.Schedule(3), []time.Duration{time.Second, time.Second}; !slices.Equal(got, want) {
+		t.Fatalf("got delays %v, want %v", got, want)
+	}
+	cfg = cfg.WithJitter(0.5)
+	cfg = cfg.WithExponentialBackoff(time.Second, 2)
+	for range 100 {
+		for i := 1; i <= 3; i++ {
+			if d := retry.Delay(cfg, i); d < cfg.MinDelay || d > cfg.MaxDelay {
+				t.Fatalf("delay %d: got %v, want it in [%v, %v]", i, d, cfg.MinDelay, cfg.MaxDelay)
+			}
+		}
+	}
+}
+
+func TestFuncAttempts(t *testing.T) {
+	retryOn := func(err error) bool { return err != nil }
+	failFirst := func(n int) func() error {
+		var calls int
+		return func() error {
+			calls++
+			if calls <= n {
+				return errors.New("boom")
+			}
+			return nil
+		}
+	}
+	for _, tc := range []struct {
+		name     string
+		cfg      retry.Config
+		fn       func() error
+		canceled bool
+		want     int
+		wantErr  bool
+	}{
+		{name: "firstTry", cfg: retry.Config{MaxAttempts: 5, RetryOn: retryOn}, fn: failFirst(0), want: 1},
+		{name: "afterRetries", cfg: retry.Config{MaxAttempts: 5, RetryOn: retryOn}, fn: failFirst(2), want: 3},
+		{name: "exhausted", cfg: retry.Config{MaxAttempts: 5, RetryOn: retryOn}, fn: failFirst(10), want: 5, wantErr: true},
+		{name: "singleAttempt", cfg: retry.Config{}, fn: failFirst(10), want: 1, wantErr: true},
+		{name: "canceledBeforeCall", cfg: retry.Config{}, fn: failFirst(0), canceled: true, want: 0, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.canceled {
+				cancel()
+			}
+			n, err := retry.FuncAttempts(ctx, tc.cfg, tc.fn)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, want error: %t", err, tc.wantErr)
+			}
+			if n != tc.want {
+				t.Fatalf("got %d attempts, want %d", n, tc.want)
+			}
+		})
+	}
+	t.Run("canceledMidway", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var calls int
+		fn := func() error {
+			calls++
+			if calls == 3 {
+				cancel()
+			}
+			return errors.New("boom")
+		}
+		n, err := retry.FuncAttempts(ctx, retry.Config{MaxAttempts: 10, RetryOn: retryOn}, fn)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("got unexpected error %v, want %v", err, context.Canceled)
+		}
+		if n != 3 {
+			t.Fatalf("got %d attempts, want 3", n)
+		}
+	})
+}
+
+func TestFuncOnRetry(t *testing.T) {
+	type call struct {
+		attempt int
+		err     string
+	}
+	var calls []call
+	var n int
+	fn := func() error {
+		n++
+		if n < 3 {
+			return fmt.Errorf("failure %d", n)
+		}
+		return nil
+	}
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return err != nil },
+		OnRetry: func(attempt int, err error) {
+			calls = append(calls, call{attempt, err.Error()})
+		},
+	}
+	if err := retry.Func(context.Background(), cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	want := []call{{1, "failure 1"}, {2, "failure 2"}}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("got OnRetry calls %v, want %v", calls, want)
+	}
+
+	calls, n = nil, -10
+	cfg.MaxAttempts = 2
+	if err := retry.Func(context.Background(), cfg, fn); err == nil {
+		t.Fatal("expected to get error from retry.Func, but got nil")
+	}
+	want = []call{{1, "failure -9"}}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("got OnRetry calls %v, want %v", calls, want)
+	}
+}
+
+func TestFuncMaxElapsedTime(t *testing.T) {
+	var calls int
+	errBoom := errors.New("boom")
+	fn := func() error { calls++; return errBoom }
+	cfg := retry.Config{
+		MaxAttempts:    1000,
+		Delay:          5 * time.Millisecond,
+		MaxElapsedTime: 20 * time.Millisecond,
+		RetryOn:        func(err error) bool { return err != nil },
+	}
+	begin := time.Now()
+	err := retry.Func(context.Background(), cfg, fn)
+	if err != errBoom {
+		t.Fatalf("got unexpected error %v, want %v", err, errBoom)
+	}
+	if d := time.Since(begin); d > 2*cfg.MaxElapsedTime {
+		t.Fatalf("retries took %v, way longer than MaxElapsedTime %v", d, cfg.MaxElapsedTime)
+	}
+	if calls < 2 || calls > 5 {
+		t.Fatalf("got %d calls, want between 2 and 5", calls)
+	}
+}
+
+func ExampleFuncVal2() {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "hello")
+	}))
+	defer srv.Close()
+
+	errUnavailable := errors.New("service unavailable")
+	fn := func() ([]byte, int, error) {
+		resp, err := http.Get(srv.URL)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err == nil && resp.StatusCode == http.StatusServiceUnavailable {
+			err = errUnavailable
+		}
+		return body, resp.StatusCode, err
+	}
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return errors.Is(err, errUnavailable) },
+	}
+	body, code, err := retry.FuncVal2(context.Background(), cfg, fn)
+	fmt.Printf("status: %d, body: %q, error: %v\n", code, body, err)
+	// Output:
+	// status: 200, body: "hello\n", error: <nil>
+}
+
+func TestFuncDecide(t *testing.T) {
+	errRateLimited := errors.New("rate limited")
+	errPermanent := errors.New("permanent")
+	var n int
+	var prev time.Time
+	var delays []time.Duration
+	fn := func() error {
+		n++
+		if !prev.IsZero() {
+			delays = append(delays, time.Since(prev))
+		}
+		prev = time.Now()
+		switch n {
+		case 1:
+			return errRateLimited
+		case 2:
+			return errors.New("transient")
+		}
+		return errPermanent
+	}
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		RetryOn:     func(error) bool { return false }, // ignored when Decide is set
+		Delay:       time.Millisecond,
+		Decide: func(attempt int, err error) retry.Decision {
+			switch {
+			case errors.Is(err, errRateLimited):
+				return retry.Decision{Retry: true, Delay: 20 * time.Millisecond}
+			case errors.Is(err, errPermanent):
+				return retry.Decision{}
+			}
+			return retry.Decision{Retry: true}
+		},
+	}
+	err := retry.Func(context.Background(), cfg, fn)
+	if err != errPermanent {
+		t.Fatalf("got unexpected error %v, want %v", err, errPermanent)
+	}
+	if n != 3 {
+		t.Fatalf("got %d calls, want 3", n)
+	}
+	if delays[0] < 20*time.Millisecond {
+		t.Fatalf("first delay is %v, want at least 20ms", delays[0])
+	}
+	if delays[1] >= 20*time.Millisecond {
+		t.Fatalf("second delay is %v, want it to be based on Config.Delay", delays[1])
+	}
+}
+
+func TestFuncCtx(t *testing.T) {
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var calls int
+	fn := func(ctx context.Context) (string, error) {
+		calls++
+		if ctx.Value(ctxKey{}) != "value" {
+			t.Fatal("fn got unexpected context")
+		}
+		if calls < 2 {
+			return "", errors.New("boom")
+		}
+		return "ok", nil
+	}
+	val, err := retry.FuncValCtx(ctx, cfg, fn)
+	if err != nil || val != "ok" {
+		t.Fatalf("got (%q, %v), want (\"ok\", nil)", val, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	err = retry.FuncCtx(ctx, cfg, func(ctx context.Context) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got unexpected error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFuncJoinErrors(t *testing.T) {
+	errs := []error{errors.New("first"), errors.New("second"), errors.New("third")}
+	var n int
+	fn := func() error { n++; return errs[n-1] }
+	cfg := retry.Config{
+		MaxAttempts: len(errs),
+		RetryOn:     func(err error) bool { return err != nil },
+		JoinErrors:  true,
+	}
+	err := retry.Func(context.Background(), cfg, fn)
+	for _, e := range errs {
+		if !errors.Is(err, e) {
+			t.Errorf("errors.Is(%v, %v) is false", err, e)
+		}
+	}
+
+	n = 0
+	errs[1] = nil
+	if err := retry.Func(context.Background(), cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+}
+
+func TestFuncCollectErrors(t *testing.T) {
+	clk := &fakeClock{auto: true}
+	errs := []error{errors.New("first"), errors.New("second"), errors.New("third")}
+	var n int
+	fn := func() error { n++; return errs[n-1] }
+	cfg := retry.Config{
+		MaxAttempts:   len(errs),
+		Delay:         time.Second,
+		RetryOn:       func(err error) bool { return err != nil },
+		CollectErrors: true,
+		JoinErrors:    true, // ignored
+	}
+	cfg = cfg.WithClock(clk)
+	err := retry.Func(context.Background(), cfg, fn)
+	var aerrs retry.AttemptErrors
+	if !errors.As(err, &aerrs) {
+		t.Fatalf("got error of type %T, want retry.AttemptErrors", err)
+	}
+	if len(aerrs) != len(errs) {
+		t.Fatalf("got %d errors, want %d", len(aerrs), len(errs))
+	}
+	for i, e := range aerrs {
+		if e.Attempt != i+1 || e.Err != errs[i] {
+			t.Errorf("entry %d: got attempt %d, error %v; want attempt %d, error %v", i, e.Attempt, e.Err, i+1, errs[i])
+		}
+		if want := (time.Time{}).Add(time.Duration(i) * time.Second); !e.At.Equal(want) {
+			t.Errorf("entry %d: got time %v, want %v", i, e.At, want)
+		}
+		if !errors.Is(err, errs[i]) {
+			t.Errorf("errors.Is(%v, %v) is false", err, errs[i])
+		}
+	}
+	if want := "attempt 1: first\nattempt 2: second\nattempt 3: third"; err.Error() != want {
+		t.Errorf("got error text %q, want %q", err.Error(), want)
+	}
+
+	n = 0
+	errs[1] = nil
+	if err := retry.Func(context.Background(), cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+}
+
+func TestFuncReturnFirstError(t *testing.T) {
+	errFirst := errors.New("connection refused")
+	errLast := errors.New("connection pool exhausted")
+	errFatal := errors.New("fatal")
+	var errs []error
+	fn := func() error { err := errs[0]; errs = errs[1:]; return err }
+	cfg := retry.Config{
+		MaxAttempts:      3,
+		RetryOn:          func(err error) bool { return err != errFatal },
+		ReturnFirstError: true,
+		WrapExhausted:    true,
+	}
+
+	errs = []error{errFirst, errLast, errLast}
+	err := retry.Func(context.Background(), cfg, fn)
+	var e *retry.ExhaustedError
+	if !errors.As(err, &e) || e.Err != errFirst || e.Attempts != 3 {
+		t.Fatalf("got error %v, want exhausted error wrapping %v", err, errFirst)
+	}
+
+	// not exhausted, so the last error is returned
+	errs = []error{errFirst, errFatal}
+	if err := retry.Func(context.Background(), cfg, fn); err != errFatal {
+		t.Fatalf("got error %v, want %v", err, errFatal)
+	}
+
+	errs = []error{errFirst, errLast, nil}
+	if err := retry.Func(context.Background(), cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+
+	cfg.ReturnFirstError = false
+	errs = []error{errFirst, errLast, errLast}
+	if err := retry.Func(context.Background(), cfg, fn); !errors.Is(err, errLast) || errors.Is(err, errFirst) {
+		t.Fatalf("got error %v, want %v", err, errLast)
+	}
+}
+
+func TestFuncValRetryable(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK}
+	var n int
+	fn := func() (int, error) { n++; return statuses[n-1], nil }
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	code, err := retry.FuncValRetryable(context.Background(), cfg, fn,
+		func(code int) bool { return code == http.StatusServiceUnavailable })
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if code != http.StatusOK || n != 3 {
+		t.Fatalf("got status %d after %d calls, want %d after 3 calls", code, n, http.StatusOK)
+	}
+}
+
+func TestFuncUntil(t *testing.T) {
+	errTemp := errors.New("temporary")
+	results := []struct {
+		s   string
+		err error
+	}{{"", nil}, {"", errTemp}, {"", nil}, {"ready", nil}}
+	var n int
+	fn := func() (string, error) { n++; return results[n-1].s, results[n-1].err }
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		RetryOn:     func(err error) bool { return errors.Is(err, errTemp) },
+	}
+	s, err := retry.FuncUntil(context.Background(), cfg, fn)
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if s != "ready" || n != 4 {
+		t.Fatalf("got %q after %d calls, want %q after 4 calls", s, n, "ready")
+	}
+
+	errFatal := errors.New("fatal")
+	n = 0
+	_, err = retry.FuncUntil(context.Background(), cfg, func() (string, error) { n++; return "", errFatal })
+	if err != errFatal || n != 1 {
+		t.Fatalf("got error %v after %d calls, want %v after 1 call", err, n, errFatal)
+	}
+}
+
+func ExampleConfig_stopOn() {
+	var n int
+	isReady := func() error {
+		n++
+		if n < 3 {
+			fmt.Printf("attempt %d, not ready\n", n)
+			return errors.New("not ready")
+		}
+		fmt.Printf("attempt %d, ready\n", n)
+		return nil
+	}
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		StopOn:      func(err error) bool { return err == nil },
+	}
+	err := retry.Func(context.Background(), cfg, isReady)
+	fmt.Println("error:", err)
+	// Output:
+	// attempt 1, not ready
+	// attempt 2, not ready
+	// attempt 3, ready
+	// error: <nil>
+}
+
+func TestFuncRetryOnN(t *testing.T) {
+	var calls int
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		RetryOn:     func(error) bool { return true }, // ignored when RetryOnN is set
+		RetryOnN:    func(attempt int, err error) bool { return err != nil && attempt < 2 },
+	}
+	n, err := retry.FuncAttempts(context.Background(), cfg, func() error { calls++; return errors.New("boom") })
+	if err == nil {
+		t.Fatal("expected to get error from retry.Func, but got nil")
+	}
+	if n != 2 || calls != 2 {
+		t.Fatalf("got %d attempts and %d calls, want 2", n, calls)
+	}
+}
+
+func TestFuncRetryOnCtx(t *testing.T) {
+	var infos []retry.AttemptInfo
+	var fallback bool
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOnN:    func(int, error) bool { return false }, // ignored when RetryOnCtx is set
+		RetryOnCtx: func(info retry.AttemptInfo, err error) bool {
+			infos = append(infos, info)
+			fallback = info.Remaining == 1 // use the fallback on the last attempt
+			return err != nil
+		},
+	}
+	var calls int
+	n, err := retry.FuncAttempts(context.Background(), cfg, func() error {
+		calls++
+		if fallback {
+			return nil
+		}
+		return errors.New("boom")
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if n != 3 || calls != 3 {
+		t.Fatalf("got %d attempts and %d calls, want 3", n, calls)
+	}
+	want := []retry.AttemptInfo{
+		{Attempt: 1, MaxAttempts: 3, Remaining: 2},
+		{Attempt: 2, MaxAttempts: 3, Remaining: 1},
+		{Attempt: 3, MaxAttempts: 3, Remaining: 0},
+	}
+	if !slices.Equal(infos, want) {
+		t.Fatalf("got %+v, want %+v", infos, want)
+	}
+}
+
+func TestFuncRetryOnElapsed(t *testing.T) {
+	errAuth := errors.New("unauthorized")
+	errUnavailable := errors.New("service unavailable")
+	cfg := retry.Config{
+		MaxAttempts: 8,
+		Delay:       3 * time.Second,
+		RetryOnN:    func(int, error) bool { return false }, // ignored when RetryOnElapsed is set
+		RetryOnElapsed: func(elapsed time.Duration, err error) bool {
+			if errors.Is(err, errAuth) {
+				return elapsed < 10*time.Second
+			}
+			return errors.Is(err, errUnavailable)
+		},
+	}
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{errAuth, 5},        // attempts at 0s, 3s, 6s, 9s, 12s
+		{errUnavailable, 8}, // until attempts run out
+	} {
+		cfg := cfg.WithClock(&fakeClock{auto: true})
+		n, err := retry.FuncAttempts(context.Background(), cfg, func() error { return tc.err })
+		if err != tc.err {
+			t.Errorf("got error %v, want %v", err, tc.err)
+		}
+		if n != tc.want {
+			t.Errorf("%v: got %d attempts, want %d", tc.err, n, tc.want)
+		}
+	}
+}
+
+func TestFuncRetryOnElapsedInitialDelay(t *testing.T) {
+	var got []time.Duration
+	cfg := retry.Config{
+		MaxAttempts:  3,
+		Delay:        time.Second,
+		InitialDelay: time.Hour,
+		RetryOnElapsed: func(elapsed time.Duration, err error) bool {
+			got = append(got, elapsed)
+			return true
+		},
+	}
+	cfg = cfg.WithClock(&fakeClock{auto: true})
+	retry.Func(context.Background(), cfg, func() error { return errors.New("boom") })
+	// elapsed time is measured from the first attempt, not from the call
+	if want := []time.Duration{0, time.Second, 2 * time.Second}; !slices.Equal(got, want) {
+		t.Fatalf("got elapsed times %v, want %v", got, want)
+	}
+}
+
+func TestFuncNoDelayOn(t *testing.T) {
+	errStale := errors.New("stale connection")
+	errBusy := errors.New("busy")
+	results := []error{errStale, errBusy, errStale, nil}
+	clk := &fakeClock{auto: true}
+	var starts []time.Duration
+	var n int
+	fn := func() error {
+		starts = append(starts, clk.Now().Sub(time.Time{}))
+		n++
+		return results[n-1]
+	}
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		Delay:       time.Second,
+		RetryOn:     func(err error) bool { return err != nil },
+		NoDelayOn:   func(err error) bool { return errors.Is(err, errStale) },
+	}
+	if err := retry.Func(context.Background(), cfg.WithClock(clk), fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	want := []time.Duration{0, 0, time.Second, time.Second}
+	if !slices.Equal(starts, want) {
+		t.Fatalf("got attempts started at %v, want %v", starts, want)
+	}
+}
+
+func TestFuncStop(t *testing.T) {
+	stop := make(chan struct{})
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		Delay:       time.Hour,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var calls int
+	fn := func() error {
+		calls++
+		time.AfterFunc(10*time.Millisecond, func() { close(stop) })
+		return errors.New("boom")
+	}
+	err := retry.FuncStop(stop, cfg, fn)
+	if err != retry.ErrStopped {
+		t.Fatalf("got unexpected error %v, want %v", err, retry.ErrStopped)
+	}
+	if calls != 1 {
+		t.Fatalf("expecting exactly 1 function call, got %d", calls)
+	}
+	if err := retry.FuncStop(nil, cfg, func() error { return nil }); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+}
+
+func TestFuncStopNoDelay(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	stop := make(chan struct{})
+	var calls int
+	err := retry.FuncStop(stop, cfg, func() error {
+		if calls++; calls == 1 {
+			close(stop)
+		}
+		return errors.New("boom")
+	})
+	if err != retry.ErrStopped {
+		t.Fatalf("got unexpected error %v, want %v", err, retry.ErrStopped)
+	}
+	if calls != 1 {
+		t.Fatalf("expecting exactly 1 function call, got %d", calls)
+	}
+
+	calls = 0
+	err = retry.FuncStop(stop, cfg, func() error { calls++; return nil })
+	if err != retry.ErrStopped {
+		t.Fatalf("got unexpected error %v, want %v", err, retry.ErrStopped)
+	}
+	if calls != 0 {
+		t.Fatalf("got %d calls with stop closed beforehand, want 0", calls)
+	}
+}
+
+func TestConfigDelayForAttempt(t *testing.T) {
+	cfg := retry.Config{Delay: time.Second, MaxDelay: 3 * time.Second}
+	for i, want := range []time.Duration{0, 0, time.Second, time.Second} {
+		if d := cfg.DelayForAttempt(i); d != want {
+			t.Errorf("fixed delay, attempt %d: got %v, want %v", i, d, want)
+		}
+	}
+	cfg = cfg.WithDelayFunc(func(i int) time.Duration { return time.Duration(i) * time.Second })
+	for i, want := range []time.Duration{0, 0, time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		if d := cfg.DelayForAttempt(i); d != want {
+			t.Errorf("delay function, attempt %d: got %v, want %v", i, d, want)
+		}
+	}
+}
+
+func TestFuncOnComplete(t *testing.T) {
+	clk := &fakeClock{auto: true}
+	var summaries []retry.Summary
+	cfg := retry.Config{
+		MaxAttempts:  3,
+		InitialDelay: time.Second,
+		Delay:        time.Minute,
+		RetryOn:      func(err error) bool { return err != nil },
+		OnComplete:   func(s retry.Summary) { summaries = append(summaries, s) },
+	}
+	errBoom := errors.New("boom")
+	err := retry.Func(context.Background(), cfg.WithClock(clk), func() error { return errBoom })
+	if err != errBoom {
+		t.Fatalf("got unexpected error %v, want %v", err, errBoom)
+	}
+	want := retry.Summary{
+		Attempts:     3,
+		TotalDelay:   time.Second + 2*time.Minute,
+		TotalElapsed: time.Second + 2*time.Minute,
+		Err:          errBoom,
+	}
+	if len(summaries) != 1 || summaries[0] != want {
+		t.Fatalf("got summaries %+v, want exactly one %+v", summaries, want)
+	}
+}
+
+// rateLimitedError carries a suggested retry interval.
+type rateLimitedError struct{ retryAfter time.Duration }
+
+func (e *rateLimitedError) Error() string { return "rate limited" }
+
+func TestConfigWithErrorDelay(t *testing.T) {
+	clk := &fakeClock{auto: true}
+	results := []error{&rateLimitedError{retryAfter: time.Minute}, errors.New("boom"), nil}
+	var starts []time.Duration
+	var n int
+	fn := func() error {
+		starts = append(starts, clk.Now().Sub(time.Time{}))
+		n++
+		return results[n-1]
+	}
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		Delay:       time.Second,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithErrorDelay(func(err error) (time.Duration, bool) {
+		var rl *rateLimitedError
+		if errors.As(err, &rl) {
+			return rl.retryAfter, true
+		}
+		return 0, false
+	})
+	if err := retry.Func(context.Background(), cfg.WithClock(clk), fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	want := []time.Duration{0, time.Minute, time.Minute + time.Second}
+	if !slices.Equal(starts, want) {
+		t.Fatalf("got attempts started at %v, want %v", starts, want)
+	}
+}
+
+func TestFuncMaxRetries(t *testing.T) {
+	fail := func() error { return errors.New("boom") }
+	retryOn := func(err error) bool { return err != nil }
+	n1, _ := retry.FuncAttempts(context.Background(), retry.Config{MaxAttempts: 3, RetryOn: retryOn}, fail)
+	n2, _ := retry.FuncAttempts(context.Background(), retry.Config{MaxRetries: 3, RetryOn: retryOn}, fail)
+	if n1 != 3 || n2 != 4 {
+		t.Fatalf("got %d attempts with MaxAttempts and %d with MaxRetries, want 3 and 4", n1, n2)
+	}
+	n, _ := retry.FuncAttempts(context.Background(), retry.Config{MaxAttempts: 2, MaxRetries: 3, RetryOn: retryOn}, fail)
+	if n != 2 {
+		t.Fatalf("got %d attempts with both MaxAttempts and MaxRetries set, want 2", n)
+	}
+}
+
+func TestConfigSchedule(t *testing.T) {
+	cfg := retry.Config{Delay: time.Second}
+	if got, want := cfg.Schedule(4), []time.Duration{time.Second, time.Second, time.Second}; !slices.Equal(got, want) {
+		t.Errorf("fixed delay: got schedule %v, want %v", got, want)
+	}
+	cfg = cfg.WithExponentialBackoff(time.Second, 2)
+	cfg.MaxDelay = 5 * time.Second
+	cfg = cfg.WithJitter(0.5)
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+	if got := cfg.Schedule(5); !slices.Equal(got, want) {
+		t.Errorf("exponential: got schedule %v, want %v", got, want)
+	}
+	if got := cfg.Schedule(1); len(got) != 0 {
+		t.Errorf("got schedule %v for a single attempt, want empty", got)
+	}
+}
+
+func TestAttemptFromContext(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var attempts []int
+	err := retry.FuncCtx(context.Background(), cfg, func(ctx context.Context) error {
+		n, ok := retry.AttemptFromContext(ctx)
+		if !ok {
+			t.Fatal("context carries no attempt number")
+		}
+		attempts = append(attempts, n)
+		return errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected to get error from retry.FuncCtx, but got nil")
+	}
+	if want := []int{1, 2, 3}; !slices.Equal(attempts, want) {
+		t.Fatalf("got attempts %v, want %v", attempts, want)
+	}
+	if _, ok := retry.AttemptFromContext(context.Background()); ok {
+		t.Fatal("AttemptFromContext reported ok for a plain context")
+	}
+}
+
+func TestFuncOnGiveUp(t *testing.T) {
+	errBoom := errors.New("boom")
+	errPermanent := errors.New("permanent")
+	type giveUp struct {
+		reason   retry.GiveUpReason
+		attempts int
+		err      error
+	}
+	var got []giveUp
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return errors.Is(err, errBoom) },
+		OnGiveUp: func(reason retry.GiveUpReason, attempts int, err error) {
+			got = append(got, giveUp{reason, attempts, err})
+		},
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		fn   func() error
+		want []giveUp
+	}{
+		{"success", context.Background(), func() error { return nil }, nil},
+		{"notRetryable", context.Background(), func() error { return errPermanent },
+			[]giveUp{{retry.NotRetryable, 1, errPermanent}}},
+		{"exhausted", context.Background(), func() error { return errBoom },
+			[]giveUp{{retry.Exhausted, 3, errBoom}}},
+		{"contextCanceled", canceled, func() error { return errBoom },
+			[]giveUp{{retry.ContextCanceled, 0, context.Canceled}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got = nil
+			_ = retry.Func(tc.ctx, cfg, tc.fn)
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("got OnGiveUp calls %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFuncValZeroOnError(t *testing.T) {
+	fn := func() (string, error) { return "partial", errors.New("boom") }
+	cfg := retry.Config{
+		MaxAttempts: 2,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	if val, err := retry.FuncVal(context.Background(), cfg, fn); err == nil || val != "partial" {
+		t.Fatalf("got (%q, %v), want the last value and an error", val, err)
+	}
+	cfg.ZeroOnError = true
+	if val, err := retry.FuncVal(context.Background(), cfg, fn); err == nil || val != "" {
+		t.Fatalf("got (%q, %v), want zero value and an error", val, err)
+	}
+	fn2 := func() (string, int, error) { return "partial", 1, errors.New("boom") }
+	if v1, v2, err := retry.FuncVal2(context.Background(), cfg, fn2); err == nil || v1 != "" || v2 != 0 {
+		t.Fatalf("got (%q, %d, %v), want zero values and an error", v1, v2, err)
+	}
+}
+
+func TestFuncUnlimited(t *testing.T) {
+	var calls int
+	fn := func() error {
+		calls++
+		if calls < 5 {
+			return errors.New("boom")
+		}
+		return nil
+	}
+	cfg := retry.Config{
+		Unlimited: true,
+		Delay:     time.Millisecond,
+		RetryOn:   func(err error) bool { return err != nil },
+	}
+	if err := retry.Func(context.Background(), cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if calls != 5 {
+		t.Fatalf("got %d calls, want 5", calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := retry.Func(ctx, cfg, func() error { return errors.New("boom") })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got unexpected error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestNilFunc(t *testing.T) {
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	configs := map[string]retry.Config{
+		"zero":      {},
+		"retries":   {MaxAttempts: 3, RetryOn: func(error) bool { return true }},
+		"unlimited": {Unlimited: true, Delay: time.Second, RetryOn: func(error) bool { return true }},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			checks := map[string]error{
+				"Func":     retry.Func(ctx, cfg, nil),
+				"FuncCtx":  retry.FuncCtx(ctx, cfg, nil),
+				"canceled": retry.Func(canceled, cfg, nil),
+			}
+			_, checks["FuncAttempts"] = retry.FuncAttempts(ctx, cfg, nil)
+			_, checks["FuncVal"] = retry.FuncVal[int](ctx, cfg, nil)
+			_, _, checks["FuncVal2"] = retry.FuncVal2[int, string](ctx, cfg, nil)
+			_, checks["FuncValCtx"] = retry.FuncValCtx[int](ctx, cfg, nil)
+			_, checks["FuncValRetryable"] = retry.FuncValRetryable[int](ctx, cfg, nil, nil)
+			_, checks["HedgedFuncVal"] = retry.HedgedFuncVal[int](ctx, cfg, time.Second, nil)
+			for fn, err := range checks {
+				if err != retry.ErrNilFunc {
+					t.Errorf("%s: got unexpected error %v, want %v", fn, err, retry.ErrNilFunc)
+				}
+			}
+		})
+	}
+	t.Run("nilRetryVal", func(t *testing.T) {
+		var calls int
+		cfg := retry.Config{MaxAttempts: 3, RetryOn: func(err error) bool { return err != nil }}
+		val, err := retry.FuncValRetryable(ctx, cfg, func() (int, error) { calls++; return 42, nil }, nil)
+		if err != nil || val != 42 || calls != 1 {
+			t.Fatalf("got (%d, %v) after %d calls, want (42, nil) after 1 call", val, err, calls)
+		}
+	})
+}
+
+func TestFuncTimeout(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 100,
+		Delay:       20 * time.Millisecond,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var calls int
+	fn := func() error {
+		calls++
+		return errors.New("boom")
+	}
+	err := retry.FuncTimeout(context.Background(), 50*time.Millisecond, cfg, fn)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got unexpected error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if calls < 1 || calls >= cfg.MaxAttempts {
+		t.Fatalf("got %d calls, want between 1 and %d", calls, cfg.MaxAttempts-1)
+	}
+}
+
+func TestDelayByError(t *testing.T) {
+	errSlow := errors.New("slow down")
+	errBusy := errors.New("busy")
+	fn := retry.DelayByError(time.Second, map[error]time.Duration{
+		errSlow: time.Minute,
+		errBusy: 10 * time.Second,
+	})
+	for _, tc := range []struct {
+		err  error
+		want time.Duration
+	}{
+		{errSlow, time.Minute},
+		{fmt.Errorf("call: %w", errSlow), time.Minute},
+		{fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", errBusy)), 10 * time.Second},
+		{errors.New("boom"), time.Second},
+	} {
+		if got, ok := fn(tc.err); !ok || got != tc.want {
+			t.Errorf("%v: got (%v, %t), want (%v, true)", tc.err, got, ok, tc.want)
+		}
+	}
+	if _, ok := retry.DelayByError(-1, nil)(errors.New("boom")); ok {
+		t.Error("negative default: got true, want false")
+	}
+}
+
+func TestConfigWithAttemptBudget(t *testing.T) {
+	now := time.Now()
+	clk := &fakeClock{now: now, auto: true}
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(2500*time.Millisecond))
+	defer cancel()
+	errBoom := errors.New("boom")
+	cfg := retry.Config{
+		MaxAttempts: 10,
+		Delay:       time.Second,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithAttemptBudget(500 * time.Millisecond)
+	var calls int
+	var reason retry.GiveUpReason
+	cfg.OnGiveUp = func(r retry.GiveUpReason, _ int, _ error) { reason = r }
+	err := retry.Func(ctx, cfg.WithClock(clk), func() error {
+		calls++
+		return errBoom
+	})
+	if err != errBoom {
+		t.Fatalf("got unexpected error %v, want %v", err, errBoom)
+	}
+	// attempts start at 0s, 1s and 2s; the next one would end past 2.5s
+	if calls != 3 {
+		t.Fatalf("got %d calls, want 3", calls)
+	}
+	if reason != retry.Exhausted {
+		t.Fatalf("got give up reason %v, want %v", reason, retry.Exhausted)
+	}
+}
+
+func TestFuncTrigger(t *testing.T) {
+	trigger := make(chan struct{})
+	cfg := retry.Config{
+		MaxAttempts: 2,
+		Delay:       time.Hour,
+		RetryOn:     func(err error) bool { return err != nil },
+		Trigger:     trigger,
+	}
+	var calls int
+	fn := func() error {
+		if calls++; calls == 1 {
+			time.AfterFunc(10*time.Millisecond, func() { trigger <- struct{}{} })
+			return errors.New("boom")
+		}
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := retry.Func(ctx, cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("got %d calls, want 2", calls)
+	}
+}
+
+func TestFuncEach(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	errBoom := errors.New("boom")
+	// item value is the number of failures before success
+	items := []int{0, 2, 5, 1}
+	calls := make(map[int]int)
+	errs := retry.FuncEach(context.Background(), cfg, items, func(_ context.Context, n int) error {
+		if calls[n]++; calls[n] <= n {
+			return errBoom
+		}
+		return nil
+	})
+	want := []error{nil, nil, errBoom, nil}
+	if !slices.Equal(errs, want) {
+		t.Fatalf("got errors %v, want %v", errs, want)
+	}
+	if calls[5] != 3 {
+		t.Fatalf("got %d calls for the exhausted item, want 3", calls[5])
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var seen []string
+		errs := retry.FuncEach(ctx, cfg, []string{"a", "b", "c"}, func(_ context.Context, s string) error {
+			seen = append(seen, s)
+			if s == "b" {
+				cancel()
+			}
+			return nil
+		})
+		want := []error{nil, nil, context.Canceled}
+		if !slices.Equal(errs, want) {
+			t.Fatalf("got errors %v, want %v", errs, want)
+		}
+		if !slices.Equal(seen, []string{"a", "b"}) {
+			t.Fatalf("got calls for %v, want [a b]", seen)
+		}
+	})
+}
+
+func TestFuncWrapContextError(t *testing.T) {
+	errBoom := errors.New("boom")
+	cfg := retry.Config{
+		MaxAttempts:      10,
+		Delay:            time.Hour,
+		RetryOn:          func(err error) bool { return err != nil },
+		WrapContextError: true,
+	}
+	cfg = cfg.WithClock(&fakeClock{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fn := func() error {
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return errBoom
+	}
+	err := retry.Func(ctx, cfg, fn)
+	for _, target := range []error{context.Canceled, errBoom} {
+		if !errors.Is(err, target) {
+			t.Errorf("errors.Is(%v, %v) is false", err, target)
+		}
+	}
+}
+
+// recordHandler is a slog.Handler that keeps all records.
+type recordHandler struct {
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.records = append(h.records, r)
+	return nil
+}
+
+func TestFuncLogger(t *testing.T) {
+	errBoom := errors.New("boom")
+	h := new(recordHandler)
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		Delay:       time.Second,
+		RetryOn:     func(err error) bool { return err != nil },
+		Logger:      slog.New(h),
+	}
+	cfg = cfg.WithClock(&fakeClock{auto: true})
+	var n int
+	fn := func() error {
+		if n++; n < 3 {
+			return errBoom
+		}
+		return nil
+	}
+	if err := retry.Func(context.Background(), cfg, fn); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if err := retry.Func(context.Background(), cfg, func() error { return errBoom }); err != errBoom {
+		t.Fatalf("got unexpected error %v, want %v", err, errBoom)
+	}
+	want := []struct {
+		level slog.Level
+		attrs string
+	}{
+		{slog.LevelDebug, "attempt=1 delay=1s err=boom"},
+		{slog.LevelDebug, "attempt=2 delay=1s err=boom"},
+		{slog.LevelInfo, "attempts=3"},
+		{slog.LevelDebug, "attempt=1 delay=1s err=boom"},
+		{slog.LevelDebug, "attempt=2 delay=1s err=boom"},
+		{slog.LevelWarn, "attempts=3 reason=exhausted err=boom"},
+	}
+	if len(h.records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(h.records), len(want))
+	}
+	for i, r := range h.records {
+		var attrs []string
+		r.Attrs(func(a slog.Attr) bool {
+			attrs = append(attrs, a.String())
+			return true
+		})
+		if got := strings.Join(attrs, " "); r.Level != want[i].level || got != want[i].attrs {
+			t.Errorf("record %d: got %v %q, want %v %q", i, r.Level, got, want[i].level, want[i].attrs)
+		}
+	}
+}
+
+func TestFuncBeforeAttempt(t *testing.T) {
+	errDisabled := errors.New("feature disabled")
+	var checked []int
+	cfg := retry.Config{
+		MaxAttempts:  10,
+		InitialDelay: time.Second,
+		Delay:        time.Second,
+		RetryOn:      func(err error) bool { return err != nil },
+		BeforeAttempt: func(attempt int) error {
+			checked = append(checked, attempt)
+			if attempt == 3 {
+				return errDisabled
+			}
+			return nil
+		},
+	}
+	clk := &fakeClock{auto: true}
+	cfg = cfg.WithClock(clk)
+	var reason retry.GiveUpReason
+	cfg.OnGiveUp = func(r retry.GiveUpReason, _ int, _ error) { reason = r }
+	var calls int
+	n, err := retry.FuncAttempts(context.Background(), cfg, func() error {
+		calls++
+		return errors.New("boom")
+	})
+	if err != errDisabled {
+		t.Fatalf("got unexpected error %v, want %v", err, errDisabled)
+	}
+	if n != 2 || calls != 2 {
+		t.Fatalf("got %d attempts and %d calls, want 2", n, calls)
+	}
+	if !slices.Equal(checked, []int{1, 2, 3}) {
+		t.Fatalf("got checks before attempts %v, want [1 2 3]", checked)
+	}
+	if reason != retry.NotRetryable {
+		t.Fatalf("got give up reason %v, want %v", reason, retry.NotRetryable)
+	}
+	// the check before attempt 3 is made before its delay
+	if got, want := clk.Now().Sub(time.Time{}), 2*time.Second; got != want {
+		t.Fatalf("got %v of delays, want %v", got, want)
+	}
+}
+
+func TestFuncContextErrorFromFn(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(error) bool { return true },
+	}
+	run := func(cfg retry.Config) (int, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		return retry.FuncAttempts(ctx, cfg, func() error {
+			cancel() // fn observes cancellation and reports it
+			return fmt.Errorf("call: %w", ctx.Err())
+		})
+	}
+	n, err := run(cfg)
+	if !errors.Is(err, context.Canceled) || err == context.Canceled {
+		t.Fatalf("got unexpected error %v, want the error returned by fn", err)
+	}
+	if n != 1 {
+		t.Fatalf("got %d attempts, want 1", n)
+	}
+
+	// with RetryContextErrors, the error is retried, and retries stop
+	// once waiting for the next attempt notices the cancellation
+	cfg.RetryContextErrors = true
+	cfg.Delay = time.Millisecond
+	n, err = run(cfg)
+	if err != context.Canceled || n != 1 {
+		t.Fatalf("got error %v after %d attempts, want %v after 1 attempt", err, n, context.Canceled)
+	}
+}
+
+func TestFuncValTrace(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var n int
+	vals, err := retry.FuncValTrace(context.Background(), cfg, func() (int, error) {
+		if n++; n < 3 {
+			return n * 10, errors.New("not yet")
+		}
+		return n * 10, nil
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if want := []int{10, 20, 30}; !slices.Equal(vals, want) {
+		t.Fatalf("got values %v, want %v", vals, want)
+	}
+
+	vals, err = retry.FuncValTrace(context.Background(), cfg, func() (int, error) {
+		return 0, errors.New("boom")
+	})
+	if err == nil {
+		t.Fatal("expected to get error from retry.FuncValTrace, but got nil")
+	}
+	if len(vals) != cfg.MaxAttempts {
+		t.Fatalf("got %d values, want %d", len(vals), cfg.MaxAttempts)
+	}
+}
+
+func TestFuncForceAllAttempts(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts:      4,
+		RetryOn:          func(error) bool { return false },
+		ForceAllAttempts: true,
+	}
+	errLast := errors.New("last")
+	var calls int
+	n, err := retry.FuncAttempts(context.Background(), cfg, func() error {
+		if calls++; calls == cfg.MaxAttempts {
+			return errLast
+		}
+		return errors.New("boom")
+	})
+	if err != errLast || n != 4 {
+		t.Fatalf("got error %v after %d attempts, want %v after 4 attempts", err, n, errLast)
+	}
+
+	if n, err := retry.FuncAttempts(context.Background(), cfg, retrytest.FailNTimes(1, nil)); err != nil || n != 2 {
+		t.Fatalf("got error %v after %d attempts, want success after 2 attempts", err, n)
+	}
+
+	errFatal := errors.New("fatal")
+	if n, err := retry.FuncAttempts(context.Background(), cfg, func() error { return retry.Unrecoverable(errFatal) }); err != errFatal || n != 1 {
+		t.Fatalf("got error %v after %d attempts, want %v after 1 attempt", err, n, errFatal)
+	}
+}
+
+func TestFuncValN(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	var calls int
+	fn := func() (string, error) {
+		if calls++; calls < 3 {
+			return "", errors.New("boom")
+		}
+		return "ok", nil
+	}
+	val, n, err := retry.FuncValN(context.Background(), cfg, fn)
+	if err != nil || val != "ok" || n != 3 {
+		t.Fatalf("got (%q, %d, %v), want (\"ok\", 3, nil)", val, n, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	_, n, err = retry.FuncValN(ctx, cfg, fn)
+	if err != context.Canceled || n != 0 || calls != 0 {
+		t.Fatalf("got error %v on attempt %d after %d calls, want %v on attempt 0", err, n, calls, context.Canceled)
+	}
+}
+
+func TestConfigAddBaseDelay(t *testing.T) {
+	cfg := retry.Config{Delay: 10 * time.Second}
+	cfg = cfg.WithLinearBackoff(time.Second)
+	if got, want := cfg.Schedule(4), []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}; !slices.Equal(got, want) {
+		t.Fatalf("got delays %v, want %v", got, want)
+	}
+	cfg.AddBaseDelay = true
+	if got, want := cfg.Schedule(4), []time.Duration{11 * time.Second, 12 * time.Second, 13 * time.Second}; !slices.Equal(got, want) {
+		t.Fatalf("got delays %v with AddBaseDelay, want %v", got, want)
+	}
+	cfg = cfg.WithDelayFunc(func(int) time.Duration { return math.MaxInt64 })
+	if got := retry.Delay(cfg, 1); got != math.MaxInt64 {
+		t.Fatalf("got delay %v, want it clamped to %v", got, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestFuncValCtxTimeout(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return errors.Is(err, context.DeadlineExceeded) },
+	}
+	var calls int
+	val, err := retry.FuncValCtxTimeout(context.Background(), cfg, 10*time.Millisecond, func(ctx context.Context) (int, error) {
+		if calls++; calls == 1 {
+			<-ctx.Done() // the first attempt hangs until its timeout
+			return 0, ctx.Err()
+		}
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("attempt %d got a done context", calls)
+		}
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if val != 42 || calls != 2 {
+		t.Fatalf("got %d after %d calls, want 42 after 2 calls", val, calls)
+	}
+}
+
+func TestFuncArg(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	endpoints := []string{"primary", "secondary"}
+	var tried []string
+	resp, err := retry.FuncArg(context.Background(), cfg,
+		func(attempt int) string { return endpoints[(attempt-1)%len(endpoints)] },
+		func(endpoint string) (string, error) {
+			tried = append(tried, endpoint)
+			if endpoint == "primary" {
+				return "", errors.New("unavailable")
+			}
+			return "response from " + endpoint, nil
+		})
+	if err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+	if resp != "response from secondary" {
+		t.Fatalf("got response %q, want %q", resp, "response from secondary")
+	}
+	if !slices.Equal(tried, endpoints) {
+		t.Fatalf("tried endpoints %v, want %v", tried, endpoints)
+	}
+}
+
+func TestFuncMaxSameError(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts:  10,
+		RetryOn:      func(err error) bool { return err != nil },
+		MaxSameError: 3,
+	}
+	errA, errB := errors.New("a"), errors.New("b")
+	for _, tc := range []struct {
+		name  string
+		errs  func(n int) error
+		calls int
+	}{
+		{"identical", func(int) error { return errA }, 3},
+		{"sameText", func(int) error { return errors.New("a") }, 3},
+		{"wrapped", func(n int) error {
+			if n%2 == 0 {
+				return fmt.Errorf("call %d: %w", n, errA)
+			}
+			return errA
+		}, 3},
+		{"alternating", func(n int) error {
+			if n%2 == 0 {
+				return errA
+			}
+			return errB
+		}, 10},
+		{"repeatsLater", func(n int) error {
+			if n < 4 {
+				return fmt.Errorf("distinct %d", n)
+			}
+			return errB
+		}, 6},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int
+			err := retry.Func(context.Background(), cfg, func() error { calls++; return tc.errs(calls) })
+			if err == nil {
+				t.Fatal("expected to get error from retry.Func, but got nil")
+			}
+			if calls != tc.calls {
+				t.Fatalf("got %d calls, want %d", calls, tc.calls)
+			}
+		})
+	}
+}
+
+// zeroDelayFunc returns a function that fails every other call, and a Config
+// that retries it without delays.
+func zeroDelayFunc() (retry.Config, func() error) {
+	errBoom := errors.New("boom")
+	var fail bool
+	fn := func() error {
+		if fail = !fail; fail {
+			return errBoom
+		}
+		return nil
+	}
+	cfg := retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	return cfg, fn
+}
+
+func TestFuncZeroDelayAllocs(t *testing.T) {
+	cfg, fn := zeroDelayFunc()
+	ctx := context.Background()
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := retry.Func(ctx, cfg, fn); err != nil {
+			t.Fatalf("got unexpected error: %v", err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("got %v allocations per call, want 0", allocs)
+	}
+}
+
+func BenchmarkFuncZeroDelay(b *testing.B) {
+	cfg, fn := zeroDelayFunc()
+	ctx := context.Background()
+	b.ReportAllocs()
+	for range b.N {
+		if err := retry.Func(ctx, cfg, fn); err != nil {
+			b.Fatalf("got unexpected error: %v", err)
+		}
+	}
+	if allocs := testing.AllocsPerRun(10, func() { retry.Func(ctx, cfg, fn) }); allocs != 0 {
+		b.Fatalf("got %v allocations per call, want 0", allocs)
+	}
+}
@@ -0,0 +1,85 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// PerErrorLimit returns a function suitable for [Config.RetryOn] that allows
+// retrying each distinct error up to n times. Errors are told apart by the
+// key function; if key is nil, the error text is used as a key. The returned
+// function reports false for a nil error.
+//
+// Counts are kept for the lifetime of the returned function, and are not
+// reset between [Func] calls. The function is safe for concurrent use, so it
+// can be shared by concurrent Func calls, in which case the counts are
+// shared too.
+func PerErrorLimit(n int, key func(error) string) func(error) bool {
+	if key == nil {
+		key = func(err error) string { return err.Error() }
+	}
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	return func(err error) bool {
+		if err == nil {
+			return false
+		}
+		k := key(err)
+		mu.Lock()
+		defer mu.Unlock()
+		counts[k]++
+		return counts[k] <= n
+	}
+}
+
+// NetRetryable is a predicate suitable for [Config.RetryOn] that reports
+// whether err is a transient network error. It reports true if err, or any
+// error it wraps:
+//
+//   - is a [net.Error] reporting a timeout, other than
+//     [context.DeadlineExceeded];
+//   - is [syscall.ECONNREFUSED] or [syscall.ECONNRESET];
+//   - is [io.ErrUnexpectedEOF].
+//
+// It reports false for all other errors, including nil.
+func NetRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// Any returns a predicate that reports true if any of preds reports true
+// for an error. With no preds, the returned predicate always reports false.
+func Any(preds ...func(error) bool) func(error) bool {
+	return func(err error) bool {
+		for _, p := range preds {
+			if p(err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// All returns a predicate that reports true if all of preds report true
+// for an error. With no preds, the returned predicate always reports true.
+func All(preds ...func(error) bool) func(error) bool {
+	return func(err error) bool {
+		for _, p := range preds {
+			if !p(err) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,99 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/artyom/retry"
+)
+
+func TestPerErrorLimit(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	var calls int
+	fn := func() error {
+		calls++
+		if calls%2 == 1 {
+			return errA
+		}
+		return errB
+	}
+	cfg := retry.Config{
+		MaxAttempts: 100,
+		RetryOn:     retry.PerErrorLimit(2, nil),
+	}
+	// a, b, a, b are retried, the third a is not
+	err := retry.Func(context.Background(), cfg, fn)
+	if err != errA {
+		t.Fatalf("got unexpected error %v, want %v", err, errA)
+	}
+	if calls != 5 {
+		t.Fatalf("got %d calls, want 5", calls)
+	}
+	if cfg.RetryOn(nil) {
+		t.Fatal("nil error is reported as retryable")
+	}
+}
+
+type timeoutError struct{ timeout bool }
+
+func (e *timeoutError) Error() string   { return "i/o timeout" }
+func (e *timeoutError) Timeout() bool   { return e.timeout }
+func (e *timeoutError) Temporary() bool { return e.timeout }
+
+func TestNetRetryable(t *testing.T) {
+	opError := func(err error) error {
+		return fmt.Errorf("wrapped: %w", &net.OpError{Op: "dial", Net: "tcp", Err: err})
+	}
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{opError(syscall.ECONNREFUSED), true},
+		{opError(syscall.ECONNRESET), true},
+		{opError(&timeoutError{timeout: true}), true},
+		{opError(&timeoutError{timeout: false}), false},
+		{opError(os.ErrDeadlineExceeded), true},
+		{opError(syscall.EACCES), false},
+		{fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), true},
+		{io.EOF, false},
+		{context.DeadlineExceeded, false},
+		{fmt.Errorf("wrapped: %w", context.Canceled), false},
+	} {
+		if got := retry.NetRetryable(tc.err); got != tc.want {
+			t.Errorf("NetRetryable(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestAnyAll(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	isA := func(err error) bool { return errors.Is(err, errA) }
+	isB := func(err error) bool { return errors.Is(err, errB) }
+	joined := errors.Join(errA, errB)
+	for _, tc := range []struct {
+		name string
+		pred func(error) bool
+		err  error
+		want bool
+	}{
+		{"anyEmpty", retry.Any(), errA, false},
+		{"anyFirst", retry.Any(isA, isB), errA, true},
+		{"anySecond", retry.Any(isA, isB), errB, true},
+		{"anyNone", retry.Any(isA, isB), io.EOF, false},
+		{"allEmpty", retry.All(), errA, true},
+		{"allBoth", retry.All(isA, isB), joined, true},
+		{"allOne", retry.All(isA, isB), errA, false},
+	} {
+		if got := tc.pred(tc.err); got != tc.want {
+			t.Errorf("%s: got %t for %v, want %t", tc.name, got, tc.err, tc.want)
+		}
+	}
+}
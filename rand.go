@@ -0,0 +1,44 @@
+package retry
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+// Rand is a source of randomness, see [Config.WithRand]. It is satisfied
+// by [math/rand.Rand].
+type Rand interface {
+	// Int63n returns a non-negative pseudo-random number in [0,n).
+	// It may panic if n <= 0.
+	Int63n(n int64) int64
+}
+
+// CryptoRand is a [Rand] that reads from the crypto/rand package, for cases
+// when delays must not be predictable. It is safe for concurrent use.
+var CryptoRand Rand = NewReaderRand(crand.Reader)
+
+// NewReaderRand returns a [Rand] that takes its random bits from r, such as
+// crypto/rand.Reader. The returned Rand panics if reading from r fails.
+// It is safe for concurrent use if r is.
+func NewReaderRand(r io.Reader) Rand { return readerRand{r: r} }
+
+type readerRand struct{ r io.Reader }
+
+func (r readerRand) Int63n(n int64) int64 {
+	if n <= 0 {
+		panic("retry: invalid argument to Int63n")
+	}
+	// reject values from the incomplete last interval to avoid modulo bias
+	limit := int64((1<<63 - 1) - (1<<63)%uint64(n))
+	var buf [8]byte
+	for {
+		if _, err := io.ReadFull(r.r, buf[:]); err != nil {
+			panic("retry: reading random bytes: " + err.Error())
+		}
+		v := int64(binary.BigEndian.Uint64(buf[:]) >> 1)
+		if v <= limit {
+			return v % n
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package retry_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+// fakeRand is a deterministic Rand that returns values from a fixed list.
+type fakeRand struct {
+	vals []int64
+	i    int
+}
+
+func (r *fakeRand) Int63n(n int64) int64 {
+	v := r.vals[r.i%len(r.vals)] % n
+	r.i++
+	return v
+}
+
+func TestWithRand(t *testing.T) {
+	cfg := retry.Config{Delay: time.Second}
+	cfg = cfg.WithJitter(0.5)
+	// 0, half, and almost all of the [0,1<<53) range used for jitter
+	cfg = cfg.WithRand(&fakeRand{vals: []int64{0, 1 << 52, 1<<53 - 1}})
+	want := []time.Duration{500 * time.Millisecond, time.Second, 1500 * time.Millisecond}
+	for i, w := range want {
+		got := retry.Delay(cfg, i+1)
+		if diff := got - w; diff < -time.Microsecond || diff > time.Microsecond {
+			t.Errorf("delay %d: got %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestNewReaderRand(t *testing.T) {
+	t.Run("deterministic", func(t *testing.T) {
+		src := []byte{0, 0, 0, 0, 0, 0, 0, 20, 0, 0, 0, 0, 0, 0, 0, 22}
+		r := retry.NewReaderRand(bytes.NewReader(src))
+		// values are shifted right by one bit: 20>>1 = 10, 22>>1 = 11
+		if got := r.Int63n(7); got != 3 {
+			t.Errorf("got %d, want 3", got)
+		}
+		if got := r.Int63n(100); got != 11 {
+			t.Errorf("got %d, want 11", got)
+		}
+	})
+	t.Run("shortRead", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic on a short read")
+			}
+		}()
+		retry.NewReaderRand(bytes.NewReader([]byte{1, 2})).Int63n(10)
+	})
+	t.Run("crypto", func(t *testing.T) {
+		for range 100 {
+			if v := retry.CryptoRand.Int63n(10); v < 0 || v >= 10 {
+				t.Fatalf("got %d, want a value in [0,10)", v)
+			}
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Retryer binds a [Config] to be reused by many calls.
+// It is safe for concurrent use if the Config is.
+type Retryer struct {
+	cfg Config
+}
+
+// New returns a new [Retryer] using cfg. It returns an error if cfg is
+// invalid, as reported by [Config.Validate].
+func New(cfg Config) (*Retryer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Retryer{cfg: cfg}, nil
+}
+
+// Do retries fn according to the config of r, see [Func].
+func (r *Retryer) Do(ctx context.Context, fn func() error) error {
+	return Func(ctx, r.cfg, fn)
+}
+
+// DoVal retries fn according to the config of r, see [FuncVal].
+func DoVal[T any](ctx context.Context, r *Retryer, fn func() (T, error)) (T, error) {
+	return FuncVal(ctx, r.cfg, fn)
+}
+
+// Schedule returns the delays between attempts made by r,
+// see [Config.Schedule].
+func (r *Retryer) Schedule(n int) []time.Duration { return r.cfg.Schedule(n) }
+
+// ScheduleBounds returns the range of delays between attempts made by r,
+// see [Config.ScheduleBounds].
+func (r *Retryer) ScheduleBounds(n int) (lo, hi []time.Duration) { return r.cfg.ScheduleBounds(n) }
@@ -0,0 +1,79 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestRetryer(t *testing.T) {
+	if _, err := retry.New(retry.Config{Delay: -time.Second}); err == nil {
+		t.Fatal("New accepted invalid config")
+	}
+	r, err := retry.New(retry.Config{
+		MaxAttempts: 3,
+		RetryOn:     func(err error) bool { return err != nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int
+	fn := func() error { calls++; return errors.New("boom") }
+	if err := r.Do(context.Background(), fn); err == nil || calls != 3 {
+		t.Fatalf("got error %v after %d calls, want error after 3 calls", err, calls)
+	}
+
+	calls = 0
+	val, err := retry.DoVal(context.Background(), r, func() (int, error) {
+		calls++
+		if calls < 2 {
+			return 0, errors.New("boom")
+		}
+		return 42, nil
+	})
+	if err != nil || val != 42 {
+		t.Fatalf("got (%d, %v), want (42, nil)", val, err)
+	}
+}
+
+func TestRetryerScheduleBounds(t *testing.T) {
+	cfg := retry.Config{
+		MaxAttempts: 6,
+		MaxDelay:    5 * time.Second,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithExponentialBackoff(time.Second, 2)
+	cfg = cfg.WithJitter(0.5)
+	r, err := retry.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sched := r.Schedule(cfg.MaxAttempts)
+	lo, hi := r.ScheduleBounds(cfg.MaxAttempts)
+	if len(lo) != len(sched) || len(hi) != len(sched) {
+		t.Fatalf("got %d/%d bounds, want %d", len(lo), len(hi), len(sched))
+	}
+	wantLo := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 2500 * time.Millisecond, 2500 * time.Millisecond}
+	if !slices.Equal(lo, wantLo) {
+		t.Fatalf("got lower bounds %v, want %v", lo, wantLo)
+	}
+	for i := range sched {
+		if lo[i] > sched[i] || sched[i] > hi[i] {
+			t.Errorf("step %d: schedule %v is outside of [%v, %v]", i+1, sched[i], lo[i], hi[i])
+		}
+	}
+	for range 100 {
+		for i := range sched {
+			if d := retry.Delay(cfg, i+1); d < lo[i] || d > hi[i] {
+				t.Fatalf("step %d: sampled delay %v is outside of [%v, %v]", i+1, d, lo[i], hi[i])
+			}
+		}
+	}
+	if got := cfg.Jitter(); got != 0.5 {
+		t.Fatalf("got jitter %v, want 0.5", got)
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/artyom/retry/retrygrpc
+
+go 1.23.0
+
+require (
+	github.com/artyom/retry v0.0.0-20261014073300-68903b38ff3d
+	google.golang.org/grpc v1.64.0
+)
+
+require (
+	golang.org/x/sys v0.18.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+// Modules depending on this one ignore the replace directive and use the
+// version required above; it only makes local development use the parent
+// directory.
+replace github.com/artyom/retry => ../
@@ -0,0 +1,29 @@
+// Package retrygrpc provides gRPC-aware helpers for the retry package.
+//
+// It is a separate module, so that the retry package itself doesn't depend
+// on gRPC.
+package retrygrpc
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Retryable is a predicate suitable for the RetryOn field of retry.Config.
+// It reports true for errors with the Unavailable, ResourceExhausted, and
+// Aborted gRPC status codes, and false for all other errors, including nil.
+// Wrapped status errors are supported.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch s.Code() {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
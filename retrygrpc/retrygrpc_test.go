@@ -0,0 +1,43 @@
+package retrygrpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/artyom/retry"
+	"github.com/artyom/retry/retrygrpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{status.Error(codes.Unavailable, "unavailable"), true},
+		{status.Error(codes.ResourceExhausted, "slow down"), true},
+		{status.Error(codes.Aborted, "aborted"), true},
+		{status.Error(codes.InvalidArgument, "bad request"), false},
+		{status.Error(codes.NotFound, "not found"), false},
+		{fmt.Errorf("wrapped: %w", status.Error(codes.Unavailable, "unavailable")), true},
+	} {
+		if got := retrygrpc.Retryable(tc.err); got != tc.want {
+			t.Errorf("Retryable(%v) = %t, want %t", tc.err, got, tc.want)
+		}
+	}
+
+	var calls int
+	cfg := retry.Config{MaxAttempts: 3, RetryOn: retrygrpc.Retryable}
+	err := retry.Func(context.Background(), cfg, func() error {
+		calls++
+		return status.Error(codes.Unavailable, "unavailable")
+	})
+	if status.Code(err) != codes.Unavailable || calls != 3 {
+		t.Fatalf("got error %v after %d calls, want Unavailable after 3 calls", err, calls)
+	}
+}
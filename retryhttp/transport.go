@@ -0,0 +1,168 @@
+// Package retryhttp provides an [http.RoundTripper] that retries requests
+// using the retry package.
+package retryhttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+// Transport is an [http.RoundTripper] that retries requests according to
+// Config. It only retries requests with methods listed in Methods, and
+// only on connection errors and responses with status codes listed in
+// StatusCodes. Delays requested by the server with the Retry-After header
+// take precedence over the ones computed from Config, including the error
+// delay set with [retry.Config.WithErrorDelay], which is still used for
+// responses without this header and for connection errors.
+//
+// Requests with a body are only retried if their GetBody field is set,
+// which is the case for requests created by [http.NewRequest] with common
+// body types.
+type Transport struct {
+	// Base is the underlying transport; if nil, [http.DefaultTransport]
+	// is used.
+	Base http.RoundTripper
+	// Config specifies the retry policy. If its RetryOn field is nil,
+	// requests are retried on responses with status codes listed in
+	// StatusCodes, and on connection errors: the ones [retry.NetRetryable]
+	// reports true for, [io.EOF], and [syscall.EPIPE]. Other transport
+	// errors, such as TLS certificate errors, are not retried.
+	Config retry.Config
+	// Methods lists request methods to retry. If empty, idempotent methods
+	// are retried: GET, HEAD, OPTIONS, TRACE, PUT, and DELETE.
+	Methods []string
+	// StatusCodes lists response status codes to retry. If empty, responses
+	// with the 429 (Too Many Requests) and all 5xx status codes are retried.
+	StatusCodes []int
+}
+
+var defaultMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodOptions,
+	http.MethodTrace,
+	http.MethodPut,
+	http.MethodDelete,
+}
+
+// RoundTrip implements the [http.RoundTripper] interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	methods := t.Methods
+	if len(methods) == 0 {
+		methods = defaultMethods
+	}
+	if !slices.Contains(methods, req.Method) {
+		return base.RoundTrip(req)
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// body can't be replayed
+		return base.RoundTrip(req)
+	}
+	cfg := t.Config
+	cfg.ZeroOnError = false // the last response is needed to return it
+	if cfg.RetryOn == nil {
+		cfg.RetryOn = func(err error) bool {
+			var se *statusError
+			if errors.As(err, &se) {
+				return true
+			}
+			return retry.NetRetryable(err) || errors.Is(err, io.EOF) ||
+				errors.Is(err, syscall.EPIPE)
+		}
+	}
+	var last *statusError // error of the last attempt, if its response is still usable
+	onRetry := cfg.OnRetry
+	cfg.OnRetry = func(attempt int, err error) {
+		var se *statusError
+		if errors.As(err, &se) {
+			discard(se.resp)
+			last = nil
+		}
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+	}
+	errorDelay := cfg.ErrorDelay()
+	cfg = cfg.WithRetryAfter(func(err error) (time.Duration, bool) {
+		var se *statusError
+		if errors.As(err, &se) {
+			if d, ok := retry.RetryAfter(se.resp); ok {
+				return d, true
+			}
+		}
+		if errorDelay != nil {
+			return errorDelay(err)
+		}
+		return 0, false
+	})
+	var calls int
+	fn := func(ctx context.Context) (*http.Response, error) {
+		calls++
+		last = nil
+		r := req
+		if calls > 1 {
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, retry.Unrecoverable(err)
+				}
+				r.Body = body
+			}
+		}
+		resp, err := base.RoundTrip(r)
+		if err != nil {
+			return nil, err
+		}
+		if t.retryStatus(resp.StatusCode) {
+			last = &statusError{resp: resp}
+			return resp, last
+		}
+		return resp, nil
+	}
+	resp, err := retry.FuncValCtx(req.Context(), cfg, fn)
+	if err == nil {
+		return resp, nil
+	}
+	// err may hold errors of earlier attempts too, such as with JoinErrors,
+	// and resp may have been discarded before waiting for an attempt that
+	// was never made, so only the last attempt tells whether resp is usable
+	if last != nil && last.resp == resp && req.Context().Err() == nil {
+		// out of retries, return the last response as is
+		return resp, nil
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil, err
+}
+
+func (t *Transport) retryStatus(code int) bool {
+	if len(t.StatusCodes) == 0 {
+		return code == http.StatusTooManyRequests || code >= 500
+	}
+	return slices.Contains(t.StatusCodes, code)
+}
+
+// statusError is used to retry responses with certain status codes.
+type statusError struct{ resp *http.Response }
+
+func (e *statusError) Error() string { return "retryhttp: response status " + e.resp.Status }
+
+// discard drains and closes the body of a response that is about to be
+// retried, so that the underlying connection can be reused.
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+}
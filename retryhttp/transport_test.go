@@ -0,0 +1,215 @@
+package retryhttp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+	"github.com/artyom/retry/retryhttp"
+)
+
+// flakyServer returns a server that responds with 503 to the first failures
+// requests, then echoes request bodies back.
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		io.Copy(w, r.Body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestTransport(t *testing.T) {
+	tr := &retryhttp.Transport{Config: retry.Config{MaxAttempts: 5, Delay: time.Hour}}
+	client := &http.Client{Transport: tr}
+
+	t.Run("replayBody", func(t *testing.T) {
+		srv, requests := flakyServer(t, 2)
+		req, err := http.NewRequest(http.MethodPut, srv.URL, bytes.NewBufferString("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+			t.Fatalf("got response %q with status %d", body, resp.StatusCode)
+		}
+		if n := requests.Load(); n != 3 {
+			t.Fatalf("got %d requests, want 3", n)
+		}
+	})
+	t.Run("exhausted", func(t *testing.T) {
+		srv, requests := flakyServer(t, 100)
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+		}
+		if n := requests.Load(); n != 5 {
+			t.Fatalf("got %d requests, want 5", n)
+		}
+	})
+	t.Run("notIdempotent", func(t *testing.T) {
+		srv, requests := flakyServer(t, 2)
+		resp, err := client.Post(srv.URL, "text/plain", bytes.NewBufferString("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("got %d requests, want 1", n)
+		}
+	})
+	t.Run("bodyNotReplayable", func(t *testing.T) {
+		srv, requests := flakyServer(t, 2)
+		req, err := http.NewRequest(http.MethodPut, srv.URL, io.NopCloser(bytes.NewBufferString("hello")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("got %d requests, want 1", n)
+		}
+	})
+	t.Run("customStatusCodes", func(t *testing.T) {
+		srv, requests := flakyServer(t, 2)
+		client := &http.Client{Transport: &retryhttp.Transport{
+			Config:      tr.Config,
+			StatusCodes: []int{http.StatusTooManyRequests},
+		}}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if n := requests.Load(); n != 1 {
+			t.Fatalf("got %d requests, want 1", n)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(srv.Close)
+		for _, cfg := range []retry.Config{
+			{MaxAttempts: 100, Delay: 50 * time.Millisecond},
+			{MaxAttempts: 100, Delay: 50 * time.Millisecond, JoinErrors: true},
+			{MaxAttempts: 100, Delay: 50 * time.Millisecond, CollectErrors: true},
+			{MaxAttempts: 100, Delay: 50 * time.Millisecond, WrapContextError: true},
+		} {
+			client := &http.Client{Transport: &retryhttp.Transport{Config: cfg}}
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			cancel()
+			if err == nil {
+				resp.Body.Close()
+				t.Fatalf("%+v: got response with status %d, want error", cfg, resp.StatusCode)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("%+v: got error %v, want %v", cfg, err, context.DeadlineExceeded)
+			}
+		}
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestTransportErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want int32
+	}{
+		{"permanent", errors.New("x509: certificate signed by unknown authority"), 1},
+		{"connectionReset", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, 3},
+		{"eof", io.EOF, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			tr := &retryhttp.Transport{
+				Base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+					calls.Add(1)
+					return nil, tc.err
+				}),
+				Config: retry.Config{MaxAttempts: 3},
+			}
+			req, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tr.RoundTrip(req); !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, want %v", err, tc.err)
+			}
+			if n := calls.Load(); n != tc.want {
+				t.Fatalf("got %d calls, want %d", n, tc.want)
+			}
+		})
+	}
+}
+
+func TestTransportErrorDelay(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	var delayCalls int
+	cfg := retry.Config{MaxAttempts: 3, Delay: time.Hour}
+	cfg = cfg.WithErrorDelay(func(error) (time.Duration, bool) {
+		delayCalls++
+		return 0, true
+	})
+	client := &http.Client{Transport: &retryhttp.Transport{Config: cfg}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	// the error delay is only used for the response without Retry-After
+	if n := requests.Load(); n != 3 || delayCalls != 1 {
+		t.Fatalf("got %d requests and %d error delay calls, want 3 and 1", n, delayCalls)
+	}
+}
@@ -0,0 +1,23 @@
+module github.com/artyom/retry/retryotel
+
+go 1.23.0
+
+require (
+	github.com/artyom/retry v0.0.0-20261014073300-68903b38ff3d
+	go.opentelemetry.io/otel v1.28.0
+	go.opentelemetry.io/otel/sdk v1.28.0
+	go.opentelemetry.io/otel/trace v1.28.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	go.opentelemetry.io/otel/metric v1.28.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
+
+// Modules depending on this one ignore the replace directive and use the
+// version required above; it only makes local development use the parent
+// directory.
+replace github.com/artyom/retry => ../
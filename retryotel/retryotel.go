@@ -0,0 +1,44 @@
+// Package retryotel provides OpenTelemetry tracing for the retry package.
+//
+// It is a separate module, so that the retry package itself doesn't depend
+// on OpenTelemetry.
+package retryotel
+
+import (
+	"context"
+
+	"github.com/artyom/retry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanName is the name of spans created by [Func].
+const SpanName = "retry"
+
+// Func calls retry.FuncCtx within a span started with tracer. An "attempt"
+// event is added to the span after each call of fn, with the attempt number
+// and the error returned, if any. The context passed to fn carries the
+// span, so spans started by fn become its children.
+//
+// If the operation fails, the error is recorded on the span and the span
+// status is set to Error.
+func Func(ctx context.Context, tracer trace.Tracer, cfg retry.Config, fn func(context.Context) error) error {
+	ctx, span := tracer.Start(ctx, SpanName)
+	defer span.End()
+	err := retry.FuncCtx(ctx, cfg, func(ctx context.Context) error {
+		err := fn(ctx)
+		attempt, _ := retry.AttemptFromContext(ctx)
+		attrs := []attribute.KeyValue{attribute.Int("retry.attempt", attempt)}
+		if err != nil {
+			attrs = append(attrs, attribute.String("retry.error", err.Error()))
+		}
+		span.AddEvent("attempt", trace.WithAttributes(attrs...))
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package retryotel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/artyom/retry"
+	"github.com/artyom/retry/retryotel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestFunc(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	errTemp := errors.New("temporary")
+	var calls int
+	err := retryotel.Func(context.Background(), tracer, cfg, func(context.Context) error {
+		if calls++; calls < 3 {
+			return errTemp
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error %v", err)
+	}
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	events := spans[0].Events()
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	for i, ev := range events {
+		if ev.Name != "attempt" {
+			t.Errorf("event %d: got name %q, want %q", i, ev.Name, "attempt")
+		}
+		var attempt int64
+		var errText string
+		for _, kv := range ev.Attributes {
+			switch kv.Key {
+			case "retry.attempt":
+				attempt = kv.Value.AsInt64()
+			case "retry.error":
+				errText = kv.Value.AsString()
+			}
+		}
+		if attempt != int64(i+1) {
+			t.Errorf("event %d: got attempt %d, want %d", i, attempt, i+1)
+		}
+		wantErr := ""
+		if i < 2 {
+			wantErr = errTemp.Error()
+		}
+		if errText != wantErr {
+			t.Errorf("event %d: got error %q, want %q", i, errText, wantErr)
+		}
+	}
+	if st := spans[0].Status(); st.Code != codes.Unset {
+		t.Errorf("got span status %v, want %v", st.Code, codes.Unset)
+	}
+}
+
+func TestFuncFailure(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("test")
+	cfg := retry.Config{
+		MaxAttempts: 2,
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	errTemp := errors.New("temporary")
+	err := retryotel.Func(context.Background(), tracer, cfg, func(context.Context) error { return errTemp })
+	if err != errTemp {
+		t.Fatalf("got unexpected error %v, want %v", err, errTemp)
+	}
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if n := len(spans[0].Events()); n != 3 { // 2 attempts + recorded error
+		t.Fatalf("got %d events, want 3", n)
+	}
+	if st := spans[0].Status(); st.Code != codes.Error {
+		t.Errorf("got span status %v, want %v", st.Code, codes.Error)
+	}
+}
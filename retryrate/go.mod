@@ -0,0 +1,13 @@
+module github.com/artyom/retry/retryrate
+
+go 1.23.0
+
+require (
+	github.com/artyom/retry v0.0.0-20261014073300-68903b38ff3d
+	golang.org/x/time v0.5.0
+)
+
+// Modules depending on this one ignore the replace directive and use the
+// version required above; it only makes local development use the parent
+// directory.
+replace github.com/artyom/retry => ../
@@ -0,0 +1,40 @@
+// Package retryrate adapts golang.org/x/time/rate limiters for use with the
+// retry package.
+//
+// It is a separate module, so that the retry package itself doesn't depend
+// on golang.org/x/time.
+package retryrate
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DelayFunc returns a delay function suitable for the WithDelayFunc method
+// of retry.Config: each call reserves a token from l and returns how long to
+// wait until the reservation can be used, so that retries happen no faster
+// than l allows. The limiter can be shared by many calls to limit the
+// overall rate of retries.
+//
+// A token is reserved even if the retry doesn't happen, for example because
+// the context is canceled during the delay. If l can never grant a token,
+// the delay is [rate.InfDuration].
+func DelayFunc(l *rate.Limiter) func(int) time.Duration {
+	return func(int) time.Duration { return reserve(l) }
+}
+
+// ErrorDelay is like [DelayFunc], but returns a function suitable for the
+// WithErrorDelay method of retry.Config, which takes precedence over other
+// delay settings. It always reports true.
+func ErrorDelay(l *rate.Limiter) func(error) (time.Duration, bool) {
+	return func(error) (time.Duration, bool) { return reserve(l), true }
+}
+
+func reserve(l *rate.Limiter) time.Duration {
+	r := l.Reserve()
+	if !r.OK() {
+		return rate.InfDuration
+	}
+	return r.Delay()
+}
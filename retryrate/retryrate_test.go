@@ -0,0 +1,50 @@
+package retryrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+	"github.com/artyom/retry/retryrate"
+	"golang.org/x/time/rate"
+)
+
+func TestDelayFunc(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	l := rate.NewLimiter(rate.Every(interval), 1)
+	delay := retryrate.DelayFunc(l)
+	if d := delay(1); d != 0 {
+		t.Fatalf("got first delay %v, want 0", d)
+	}
+	for i := 2; i <= 3; i++ {
+		want := time.Duration(i-1) * interval
+		if d := delay(i); d > want || d < want-interval/2 {
+			t.Fatalf("delay %d: got %v, want about %v", i, d, want)
+		}
+	}
+	if d := retryrate.DelayFunc(rate.NewLimiter(rate.Every(interval), 0))(1); d != rate.InfDuration {
+		t.Fatalf("got delay %v for a limiter with zero burst, want %v", d, rate.InfDuration)
+	}
+}
+
+func TestErrorDelay(t *testing.T) {
+	const interval = 20 * time.Millisecond
+	l := rate.NewLimiter(rate.Every(interval), 1)
+	cfg := retry.Config{
+		MaxAttempts: 4,
+		Delay:       time.Hour, // overridden by the limiter
+		RetryOn:     func(err error) bool { return err != nil },
+	}
+	cfg = cfg.WithErrorDelay(retryrate.ErrorDelay(l))
+	begin := time.Now()
+	n, err := retry.FuncAttempts(context.Background(), cfg, func() error { return errors.New("boom") })
+	if err == nil || n != 4 {
+		t.Fatalf("got error %v after %d attempts, want error after 4 attempts", err, n)
+	}
+	// the first retry uses the initial burst, the other two wait
+	if elapsed := time.Since(begin); elapsed < interval || elapsed > time.Second {
+		t.Fatalf("retries took %v, want about %v", elapsed, 2*interval)
+	}
+}
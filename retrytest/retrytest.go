@@ -0,0 +1,97 @@
+// Package retrytest provides helpers for testing code that uses the retry
+// package.
+package retrytest
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+// ErrInjected is returned by functions created with [FailNTimes] on their
+// failing calls.
+var ErrInjected = errors.New("retrytest: injected failure")
+
+// FailNTimes returns a function that returns [ErrInjected] on the first n
+// calls, and then returns then on all subsequent calls; pass nil as then to
+// simulate eventual success. The returned function is safe for concurrent
+// use.
+func FailNTimes(n int, then error) func() error {
+	var mu sync.Mutex
+	var calls int
+	return func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		if calls++; calls <= n {
+			return ErrInjected
+		}
+		return then
+	}
+}
+
+// RecordDelays is a retry.Clock that doesn't wait: its timers fire right
+// away, advancing the clock by the timer duration, and their durations are
+// recorded. Use it with the WithClock method of retry.Config to make tests
+// fast and deterministic, and to check the delays between attempts.
+//
+// Delays that are not positive are not waited for, and so aren't recorded.
+//
+// RecordDelays is safe for concurrent use.
+type RecordDelays struct {
+	mu     sync.Mutex
+	now    time.Time
+	delays []time.Duration
+}
+
+// NewRecordDelays returns a new [RecordDelays] clock set to the current time.
+func NewRecordDelays() *RecordDelays { return &RecordDelays{now: time.Now()} }
+
+// Delays returns the durations of all timers started so far, in order.
+func (c *RecordDelays) Delays() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.delays)
+}
+
+// Now returns the current time of the clock.
+func (c *RecordDelays) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer returns a timer that fires right away, and records d.
+func (c *RecordDelays) NewTimer(d time.Duration) retry.Timer {
+	t := &timer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+type timer struct {
+	clock *RecordDelays
+	c     chan time.Time
+}
+
+func (t *timer) C() <-chan time.Time { return t.c }
+
+func (t *timer) Stop() bool {
+	select {
+	case <-t.c:
+	default:
+	}
+	return false
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.Stop()
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.delays = append(c.delays, d)
+	t.c <- c.now
+	return false
+}
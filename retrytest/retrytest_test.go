@@ -0,0 +1,47 @@
+package retrytest_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+	"github.com/artyom/retry/retrytest"
+)
+
+func TestFailNTimes(t *testing.T) {
+	errLast := errors.New("last")
+	fn := retrytest.FailNTimes(2, errLast)
+	for i, want := range []error{retrytest.ErrInjected, retrytest.ErrInjected, errLast, errLast} {
+		if err := fn(); err != want {
+			t.Fatalf("call %d: got error %v, want %v", i+1, err, want)
+		}
+	}
+	if err := retrytest.FailNTimes(0, nil)(); err != nil {
+		t.Fatalf("got unexpected error: %v", err)
+	}
+}
+
+func TestRecordDelays(t *testing.T) {
+	clk := retrytest.NewRecordDelays()
+	start := clk.Now()
+	cfg := retry.Config{
+		MaxAttempts: 5,
+		RetryOn:     func(err error) bool { return errors.Is(err, retrytest.ErrInjected) },
+	}
+	cfg = cfg.WithExponentialBackoff(time.Second, 2)
+	cfg = cfg.WithClock(clk)
+	n, err := retry.FuncAttempts(context.Background(), cfg, retrytest.FailNTimes(3, nil))
+	if err != nil || n != 4 {
+		t.Fatalf("got error %v after %d attempts, want success after 4 attempts", err, n)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if got := clk.Delays(); !slices.Equal(got, want) {
+		t.Fatalf("got delays %v, want %v", got, want)
+	}
+	if got := clk.Now().Sub(start); got != 7*time.Second {
+		t.Fatalf("clock advanced by %v, want %v", got, 7*time.Second)
+	}
+}
@@ -0,0 +1,66 @@
+package retry
+
+import "errors"
+
+// Validate reports obviously broken configurations. Calling it is optional:
+// [Func] works with any Config, but some of them may not do what the caller
+// intended. Validate does not modify the Config.
+//
+// The following configurations are considered invalid:
+//
+//   - negative Delay, InitialDelay, MaxDelay, MinDelay, or MaxElapsedTime;
+//   - positive MaxDelay smaller than Delay or MinDelay;
+//   - MaxAttempts greater than 1 or positive MaxRetries with none of
+//     RetryOn, StopOn, RetryOnN, RetryOnCtx, RetryOnElapsed, Decide, or
+//     ForceAllAttempts set, as no retries would be made;
+//   - both MaxAttempts and MaxRetries set;
+//   - Unlimited set along with MaxAttempts or MaxRetries;
+//   - both RetryOn and StopOn set;
+//   - negative MaxAttempts with Delay or a delay function set, as such
+//     delays would never be used.
+//
+// Zero MaxAttempts, zero durations, as well as not positive MaxAttempts
+// without any delays set are valid, and get their documented defaults.
+//
+// If more than one problem is found, the returned error combines them
+// with [errors.Join].
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Delay < 0 {
+		errs = append(errs, errors.New("retry: negative Delay"))
+	}
+	if c.InitialDelay < 0 {
+		errs = append(errs, errors.New("retry: negative InitialDelay"))
+	}
+	if c.MaxDelay < 0 {
+		errs = append(errs, errors.New("retry: negative MaxDelay"))
+	}
+	if c.MaxElapsedTime < 0 {
+		errs = append(errs, errors.New("retry: negative MaxElapsedTime"))
+	}
+	if c.MaxDelay > 0 && c.MaxDelay < c.Delay {
+		errs = append(errs, errors.New("retry: MaxDelay is smaller than Delay"))
+	}
+	if c.MinDelay < 0 {
+		errs = append(errs, errors.New("retry: negative MinDelay"))
+	}
+	if c.MaxDelay > 0 && c.MaxDelay < c.MinDelay {
+		errs = append(errs, errors.New("retry: MaxDelay is smaller than MinDelay"))
+	}
+	if c.maxAttempts() > 1 && c.RetryOn == nil && c.StopOn == nil && c.RetryOnN == nil && c.RetryOnCtx == nil && c.RetryOnElapsed == nil && c.Decide == nil && !c.ForceAllAttempts {
+		errs = append(errs, errors.New("retry: retries are allowed, but no retry predicate is set"))
+	}
+	if c.MaxAttempts > 0 && c.MaxRetries > 0 {
+		errs = append(errs, errors.New("retry: both MaxAttempts and MaxRetries are set"))
+	}
+	if c.Unlimited && (c.MaxAttempts > 0 || c.MaxRetries > 0) {
+		errs = append(errs, errors.New("retry: Unlimited is set along with MaxAttempts or MaxRetries"))
+	}
+	if c.RetryOn != nil && c.StopOn != nil {
+		errs = append(errs, errors.New("retry: both RetryOn and StopOn are set"))
+	}
+	if c.MaxAttempts < 0 && (c.Delay != 0 || c.delayFn != nil || c.newDelayFn != nil) {
+		errs = append(errs, errors.New("retry: negative MaxAttempts with delays set"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,52 @@
+package retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/artyom/retry"
+)
+
+func TestConfigValidate(t *testing.T) {
+	retryOn := func(err error) bool { return err != nil }
+	var base retry.Config
+	linear := base.WithLinearBackoff(time.Second)
+	linear.MaxAttempts = -1
+	for _, tc := range []struct {
+		name  string
+		cfg   retry.Config
+		valid bool
+	}{
+		{name: "empty", cfg: retry.Config{}, valid: true},
+		{name: "typical", cfg: retry.Config{MaxAttempts: 3, RetryOn: retryOn, Delay: time.Second, MaxDelay: time.Minute}, valid: true},
+		{name: "negativeMaxAttempts", cfg: retry.Config{MaxAttempts: -1}, valid: true},
+		{name: "negativeDelay", cfg: retry.Config{MaxAttempts: 3, RetryOn: retryOn, Delay: -time.Second}},
+		{name: "negativeInitialDelay", cfg: retry.Config{InitialDelay: -time.Second}},
+		{name: "negativeMaxElapsedTime", cfg: retry.Config{MaxElapsedTime: -time.Second}},
+		{name: "maxDelayBelowDelay", cfg: retry.Config{MaxAttempts: 3, RetryOn: retryOn, Delay: time.Minute, MaxDelay: time.Second}},
+		{name: "minDelay", cfg: retry.Config{MinDelay: time.Second, MaxDelay: time.Minute}, valid: true},
+		{name: "negativeMinDelay", cfg: retry.Config{MinDelay: -time.Second}},
+		{name: "maxDelayBelowMinDelay", cfg: retry.Config{MinDelay: time.Minute, MaxDelay: time.Second}},
+		{name: "noRetryOn", cfg: retry.Config{MaxAttempts: 3}},
+		{name: "forceAllAttempts", cfg: retry.Config{MaxAttempts: 3, ForceAllAttempts: true}, valid: true},
+		{name: "maxRetries", cfg: retry.Config{MaxRetries: 2, RetryOn: retryOn}, valid: true},
+		{name: "maxRetriesNoRetryOn", cfg: retry.Config{MaxRetries: 2}},
+		{name: "maxAttemptsAndMaxRetries", cfg: retry.Config{MaxAttempts: 3, MaxRetries: 2, RetryOn: retryOn}},
+		{name: "unlimited", cfg: retry.Config{Unlimited: true, RetryOn: retryOn}, valid: true},
+		{name: "unlimitedWithMaxAttempts", cfg: retry.Config{Unlimited: true, MaxAttempts: 3, RetryOn: retryOn}},
+		{name: "stopOn", cfg: retry.Config{MaxAttempts: 3, StopOn: retryOn}, valid: true},
+		{name: "retryOnAndStopOn", cfg: retry.Config{MaxAttempts: 3, RetryOn: retryOn, StopOn: retryOn}},
+		{name: "negativeMaxAttemptsWithDelayFunc", cfg: linear},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := tc.cfg
+			err := tc.cfg.Validate()
+			if (err == nil) != tc.valid {
+				t.Fatalf("got error %v, want valid: %t", err, tc.valid)
+			}
+			if tc.cfg.MaxAttempts != before.MaxAttempts || tc.cfg.Delay != before.Delay {
+				t.Fatal("Validate modified the Config")
+			}
+		})
+	}
+}